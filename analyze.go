@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wozniakjan/test_eval/markdown"
+	"github.com/wozniakjan/test_eval/metrics"
+	"github.com/wozniakjan/test_eval/otlp"
+	"github.com/wozniakjan/test_eval/top"
+	"github.com/wozniakjan/test_eval/trace"
+)
+
+func analyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	out := fs.String("o", "out", "Output folder for bottlenecks")
+	count := fs.Int("c", 5, "Show 'c' slowest tests")
+	windowSize := fs.Int("w", 5, "Window size")
+	threshold := fs.Float64("t", 120, "Threshold in seconds to identify windows/bottleneck")
+	detect := fs.String("detect", "window", "Detection of the slow parts: window, the -w timestamped lines taking more than -t, or gap, more than -t between two timestamped lines")
+	traceFile := fs.String("trace", "", "Write the tests as Chrome Trace Event JSON to the file, e.g. trace.json for chrome://tracing or Perfetto")
+	otlpFile := fs.String("otlp", "", "Write the tests as OTLP/JSON traces to the file")
+	otlpEndpoint := fs.String("otlp-endpoint", "", "Send the tests as traces to an OTLP/HTTP collector, e.g. http://localhost:4318")
+	markdownFile := fs.String("markdown", "", "Write the summary and the 'c' slowest tests as a Markdown report to the file")
+	source := fs.String("source", markdown.Origin, "Link the tests in the Markdown report to their source under this URL, no links when empty")
+	metricsFile := fs.String("metrics", "", "Write the test durations as OpenMetrics text to the file")
+	pushgateway := fs.String("pushgateway", "", "Push the test durations to a Pushgateway, e.g. http://localhost:9091")
+	job := fs.String("job", "", "Job name for the exported traces and metrics, taken from the log name when empty")
+	build := fs.Int("build", 0, "Build ID for the exported traces, taken from the log name when 0")
+	in := inputFlags(fs)
+	th := thresholdFlags(fs)
+	fs.Parse(args)
+	*job, *build = logBuild(*in.file, *job, *build)
+
+	opts, err := th.options(top.Options{WindowSize: *windowSize, Threshold: top.Seconds(*threshold), Detection: top.Detection(*detect)})
+	if err != nil {
+		return err
+	}
+	report, err := in.load(opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*out, 0777); err != nil {
+		return err
+	}
+	summary := report.Summary()
+	if err := printSummary(*out, summary); err != nil {
+		return err
+	}
+	if *traceFile != "" {
+		if err := writeFile(*traceFile, func(w io.Writer) error { return trace.Write(w, report) }); err != nil {
+			return err
+		}
+	}
+	tests := slowest(report, *count)
+	if err := printTop(*out, tests); err != nil {
+		return err
+	}
+	if err := printStats(*out, tests); err != nil {
+		return err
+	}
+	if *markdownFile != "" {
+		if err := writeFile(*markdownFile, func(w io.Writer) error { return markdown.Write(w, summary, tests, *source) }); err != nil {
+			return err
+		}
+	}
+	if *metricsFile != "" {
+		if err := writeFile(*metricsFile, func(w io.Writer) error { return metrics.Write(w, report, *job) }); err != nil {
+			return err
+		}
+	}
+	if *pushgateway != "" {
+		if err := metrics.Push(&http.Client{Timeout: time.Minute}, *pushgateway, *job, report); err != nil {
+			return err
+		}
+	}
+	if *otlpFile == "" && *otlpEndpoint == "" {
+		return nil
+	}
+	traces := otlp.New(report, otlp.Build{Job: *job, ID: *build})
+	if *otlpFile != "" {
+		if err := writeFile(*otlpFile, func(w io.Writer) error { return otlp.Write(w, traces) }); err != nil {
+			return err
+		}
+	}
+	if *otlpEndpoint != "" {
+		return otlp.Send(&http.Client{Timeout: time.Minute}, *otlpEndpoint, traces)
+	}
+	return nil
+}
+
+// slowest sorts the tests from slowest to fastest and returns the first
+// count of them, all when count is less than 1.
+func slowest(r *top.Report, count int) []top.Test {
+	sort.SliceStable(r.Tests, func(i, j int) bool { return r.Tests[i].Time > r.Tests[j].Time })
+	if count < 1 || count > len(r.Tests) {
+		count = len(r.Tests)
+	}
+	return r.Tests[0:count]
+}
+
+func resultFile(out string, i int, t top.Test) string {
+	fileName := strings.Replace(t.Name(), `/`, `_`, -1)
+	if t.Spec != "" {
+		fileName = "_" + fileName
+	}
+	if len(fileName) > 200 {
+		// spec names from JUnit may be longer than the file name limit
+		fileName = fileName[:200]
+	}
+	return filepath.Join(out, fmt.Sprintf("%04d", i)+"_"+fmt.Sprintf("%v", t.Time)+fileName)
+}
+
+func writeResult(out string, i int, t top.Test) error {
+	f, err := os.Create(resultFile(out, i, t))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+	fmt.Fprintf(w, "time: %vs\n", t.Time)
+	if t.Status != "" {
+		fmt.Fprintf(w, "status: %v\n", t.Status)
+	}
+	if t.Node != 0 {
+		fmt.Fprintf(w, "node: %v\n", t.Node)
+	}
+	fmt.Fprintf(w, "threshold: %vs\n", t.Threshold.Seconds())
+	for _, p := range t.Phases.Phases {
+		if p.End.IsZero() {
+			fmt.Fprintf(w, "%v%v: unfinished\n  %v\n", strings.Repeat("  ", p.Depth), p.Name, p.StartLine)
+			continue
+		}
+		fmt.Fprintf(w, "%v%v: %vs\n  %v\n  %v\n",
+			strings.Repeat("  ", p.Depth),
+			p.Name,
+			p.Duration().Seconds(),
+			p.StartLine,
+			p.EndLine)
+	}
+	for _, l := range t.Phases.Unmatched {
+		fmt.Fprintf(w, "phase end without start:\n  %v\n", l)
+	}
+	for _, l := range t.Phases.Invalid {
+		fmt.Fprintf(w, "phase time not in the time format of the rule:\n  %v\n", l)
+	}
+	if len(t.Commands) > 0 {
+		fmt.Fprintf(w, "\nCommands\n")
+	}
+	for _, c := range t.Commands {
+		fmt.Fprintf(w, "%8.3fs oc %v", c.Time, c.Verb)
+		if c.Resource != "" {
+			fmt.Fprintf(w, " %v", c.Resource)
+		}
+		if c.Namespace != "" {
+			fmt.Fprintf(w, " -n %v", c.Namespace)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	if len(t.Waits) > 0 {
+		fmt.Fprintf(w, "\nWaits\n")
+	}
+	for _, wt := range t.Waits {
+		if wt.Outcome == "" {
+			fmt.Fprintf(w, "%8.3fs %v\n", wt.Time, wt.Object)
+			continue
+		}
+		fmt.Fprintf(w, "%8.3fs %v %v\n", wt.Time, wt.Object, wt.Outcome)
+	}
+	for i, b := range t.Windows {
+		if b.Gap {
+			fmt.Fprintf(w, "\nGap %v - %vs\nbefore: %v\nafter:  %v\n", i, b.Time().Seconds(), b.TimedWindow[0].Line, b.TimedWindow[1].Line)
+			continue
+		}
+		fmt.Fprintf(w, "\nWindow %v - %vs\n", i, b.Time().Seconds())
+		for _, l := range b.TimedWindow {
+			fmt.Fprintf(w, "%v\n", l.Line)
+		}
+	}
+
+	fmt.Fprintf(w, "\n\nEntire output:\n")
+	for _, l := range t.Lines {
+		fmt.Fprintf(w, "%v\n", l)
+	}
+	return nil
+}
+
+// writeFile creates the file and writes it with write.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func printTop(out string, tests []top.Test) error {
+	for i, t := range tests {
+		if err := writeResult(out, i+1, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printSummary writes the summary to stdout, summary.txt and summary.json.
+func printSummary(out string, s top.Summary) error {
+	f, err := os.Create(filepath.Join(out, "summary.txt"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := s.Write(os.Stdout); err != nil {
+		return err
+	}
+	if err := s.Write(f); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(out, "summary.json"), data, 0666)
+}
+
+func printStats(out string, tests []top.Test) error {
+	f, err := os.Create(filepath.Join(out, "stats.json"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+	allBlocks := make([]top.Blocks, 0)
+	for _, t := range tests {
+		allBlocks = append(allBlocks, t.Blocks)
+	}
+	json, err := json.MarshalIndent(allBlocks, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(json)
+	return err
+}
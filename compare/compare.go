@@ -0,0 +1,234 @@
+// Package compare finds the tests that got slower between two builds.
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+// timestampRegexp matches the timestamp at the start of a line, e.g.
+// `Apr  3 11:48:35.747: ` or `2018-04-03T11:49:00Z `.
+var timestampRegexp = regexp.MustCompile(`^([A-Z][a-z]{2} +[0-9]{1,2} +|[0-9]{4}-[0-9]{2}-[0-9]{2}T)[0-9:.]+Z?:? *`)
+
+// Status of a test in the new build compared to the old one.
+const (
+	Changed = "changed"
+	New     = "new"
+	Removed = "removed"
+	NowSlow = "now slow"
+)
+
+// BlockDelta is the difference of the slow blocks starting at the same line,
+// or of all the fast blocks of a test.
+type BlockDelta struct {
+	BlockType string `json:"blockType"`
+	// Line is the first line of the slow block without its timestamp.
+	Line  string  `json:"line,omitempty"`
+	Old   float64 `json:"old"`
+	New   float64 `json:"new"`
+	Delta float64 `json:"delta"`
+}
+
+// TestDelta is the difference of a test duration in seconds.
+type TestDelta struct {
+	Name   string       `json:"name"`
+	Status string       `json:"status"`
+	Old    float64      `json:"old"`
+	New    float64      `json:"new"`
+	Delta  float64      `json:"delta"`
+	Blocks []BlockDelta `json:"blocks,omitempty"`
+}
+
+// Load reads stats.json, or parses a log when the file does not end with
+// .json. stats.json has only the -c slowest tests of analyze, it is rejected
+// when summary.json next to it counts more tests.
+func Load(path string, opts top.Options) ([]top.Blocks, error) {
+	if strings.HasSuffix(path, ".json") {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		stats := make([]top.Blocks, 0)
+		if err := json.Unmarshal(data, &stats); err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		}
+		if s, err := top.ReadSummary(filepath.Join(filepath.Dir(path), "summary.json")); err == nil && s.Tests > len(stats) {
+			return nil, fmt.Errorf("%v: %v of %v tests, run analyze with -c -1 or compare the logs", path, len(stats), s.Tests)
+		}
+		return stats, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := top.Parse(f, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	stats := make([]top.Blocks, 0, len(r.Tests))
+	for _, t := range r.Tests {
+		stats = append(stats, t.Blocks)
+	}
+	return stats, nil
+}
+
+func duration(b top.Blocks) float64 {
+	if b.Time > 0 || len(b.Blocks) == 0 {
+		return b.Time
+	}
+	// stats.json written before the test time was recorded
+	return b.Blocks[len(b.Blocks)-1].End
+}
+
+func hasSlow(b top.Blocks) bool {
+	for _, bl := range b.Blocks {
+		if bl.BlockType == "slow" {
+			return true
+		}
+	}
+	return false
+}
+
+// byName indexes the tests by name, repeated names get a " #n" suffix.
+func byName(stats []top.Blocks) (map[string]top.Blocks, []string) {
+	m := make(map[string]top.Blocks)
+	names := make([]string, 0, len(stats))
+	seen := make(map[string]int)
+	for _, s := range stats {
+		name := s.Name
+		if seen[s.Name]++; seen[s.Name] > 1 {
+			name = fmt.Sprintf("%v #%v", s.Name, seen[s.Name])
+		}
+		m[name] = s
+		names = append(names, name)
+	}
+	return m, names
+}
+
+// blockLine returns the first line of a block without its timestamp.
+func blockLine(b top.Block) string {
+	if len(b.Lines) == 0 {
+		return ""
+	}
+	return timestampRegexp.ReplaceAllString(b.Lines[0], "")
+}
+
+// blockDeltas returns the difference of all the fast blocks and of the slow
+// blocks matched by their first line, in the order of the new build.
+func blockDeltas(old, new top.Blocks) []BlockDelta {
+	fast := BlockDelta{BlockType: "fast"}
+	slow := make(map[string][]top.Block)
+	for _, b := range old.Blocks {
+		if b.BlockType == "slow" {
+			slow[blockLine(b)] = append(slow[blockLine(b)], b)
+		} else {
+			fast.Old += b.End - b.Start
+		}
+	}
+	deltas := []BlockDelta{fast}
+	for _, b := range new.Blocks {
+		if b.BlockType != "slow" {
+			deltas[0].New += b.End - b.Start
+			continue
+		}
+		d := BlockDelta{BlockType: "slow", Line: blockLine(b), New: b.End - b.Start}
+		if o := slow[d.Line]; len(o) > 0 {
+			d.Old = o[0].End - o[0].Start
+			slow[d.Line] = o[1:]
+		}
+		deltas = append(deltas, d)
+	}
+	for _, b := range old.Blocks {
+		if l := blockLine(b); b.BlockType == "slow" && len(slow[l]) > 0 {
+			deltas = append(deltas, BlockDelta{BlockType: "slow", Line: l, Old: slow[l][0].End - slow[l][0].Start})
+			slow[l] = slow[l][1:]
+		}
+	}
+	for i := range deltas {
+		deltas[i].Delta = deltas[i].New - deltas[i].Old
+	}
+	return deltas
+}
+
+// Compare matches the tests by name and returns their differences, the
+// biggest regression first.
+func Compare(old, new []top.Blocks) []TestDelta {
+	oldTests, oldNames := byName(old)
+	newTests, newNames := byName(new)
+	deltas := make([]TestDelta, 0)
+	for _, name := range newNames {
+		n := newTests[name]
+		o, ok := oldTests[name]
+		if !ok {
+			deltas = append(deltas, TestDelta{name, New, 0, duration(n), duration(n), nil})
+			continue
+		}
+		status := Changed
+		if hasSlow(n) && !hasSlow(o) {
+			status = NowSlow
+		}
+		deltas = append(deltas, TestDelta{name, status, duration(o), duration(n), duration(n) - duration(o), blockDeltas(o, n)})
+	}
+	for _, name := range oldNames {
+		if _, ok := newTests[name]; !ok {
+			o := oldTests[name]
+			deltas = append(deltas, TestDelta{name, Removed, duration(o), 0, -duration(o), nil})
+		}
+	}
+	sort.SliceStable(deltas, func(i, j int) bool { return deltas[i].Delta > deltas[j].Delta })
+	return deltas
+}
+
+func round(s float64) float64 {
+	return math.Round(s*1000) / 1000
+}
+
+func signed(s float64) string {
+	if s = round(s); s >= 0 {
+		return "+" + strconv.FormatFloat(s, 'f', -1, 64) + "s"
+	}
+	return strconv.FormatFloat(s, 'f', -1, 64) + "s"
+}
+
+// WriteText writes the differences as a table.
+func WriteText(w io.Writer, deltas []TestDelta) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "DELTA\tOLD\tNEW\tSTATUS\tTEST\n")
+	for _, d := range deltas {
+		fmt.Fprintf(tw, "%v\t%vs\t%vs\t%v\t%v\n", signed(d.Delta), round(d.Old), round(d.New), d.Status, d.Name)
+		for _, b := range d.Blocks {
+			if round(b.Delta) == 0 {
+				continue
+			}
+			name := b.BlockType
+			if b.Line != "" {
+				name += " " + b.Line
+			}
+			fmt.Fprintf(tw, "\t\t\t\t  %v: %vs -> %vs (%v)\n", name, round(b.Old), round(b.New), signed(b.Delta))
+		}
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the differences as json.
+func WriteJSON(w io.Writer, deltas []TestDelta) error {
+	data, err := json.MarshalIndent(deltas, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,125 @@
+package compare
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+func stats(name string, time float64, blocks ...top.Block) top.Blocks {
+	return top.Blocks{Name: name, Time: time, Blocks: blocks}
+}
+
+func TestCompare(t *testing.T) {
+	old := []top.Blocks{
+		stats("pipeline.go:437", 300, top.Block{Start: 0, End: 100, BlockType: "fast"}, top.Block{Start: 100, End: 300, BlockType: "slow"}),
+		stats("digest.go:65", 60, top.Block{Start: 0, End: 60, BlockType: "fast"}),
+		stats("removed.go:1", 10, top.Block{Start: 0, End: 10, BlockType: "fast"}),
+	}
+	new := []top.Blocks{
+		stats("digest.go:65", 200, top.Block{Start: 0, End: 10, BlockType: "fast"}, top.Block{Start: 10, End: 200, BlockType: "slow"}),
+		stats("pipeline.go:437", 250, top.Block{Start: 0, End: 100, BlockType: "fast"}, top.Block{Start: 100, End: 250, BlockType: "slow"}),
+		stats("new.go:1", 5, top.Block{Start: 0, End: 5, BlockType: "fast"}),
+	}
+	deltas := Compare(old, new)
+	expects := []struct {
+		name   string
+		status string
+		delta  float64
+	}{
+		{"digest.go:65", NowSlow, 140},
+		{"new.go:1", New, 5},
+		{"removed.go:1", Removed, -10},
+		{"pipeline.go:437", Changed, -50},
+	}
+	if len(deltas) != len(expects) {
+		t.Fatalf("Expected %v deltas, got %v", len(expects), deltas)
+	}
+	for i, e := range expects {
+		d := deltas[i]
+		if d.Name != e.name || d.Status != e.status || d.Delta != e.delta {
+			t.Errorf("Expected %v %v %v, got %v %v %v", e.name, e.status, e.delta, d.Name, d.Status, d.Delta)
+		}
+	}
+	if b := deltas[3].Blocks; len(b) != 2 || b[0].Delta != 0 || b[1].Delta != -50 {
+		t.Errorf("Unexpected block deltas %v", b)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, deltas); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "slow: 200s -> 150s (-50s)") {
+		t.Errorf("Expected block delta in text output, got\n%v", buf.String())
+	}
+	if !strings.Contains(buf.String(), "+140s") {
+		t.Errorf("Expected signed regression in text output, got\n%v", buf.String())
+	}
+}
+
+func TestBlockDeltas(t *testing.T) {
+	block := func(line string, start, end float64, blockType string) top.Block {
+		return top.Block{Lines: []string{line}, Start: start, End: end, BlockType: blockType}
+	}
+	old := stats("a.go:1", 100,
+		block("Apr  3 11:00:00.000: INFO: start", 0, 10, "fast"),
+		block("Apr  3 11:00:10.000: INFO: Waiting for build", 10, 70, "slow"),
+		block("Apr  3 11:01:10.000: INFO: Running 'oc delete'", 70, 100, "slow"),
+	)
+	new := stats("a.go:1", 150,
+		block("Apr  4 09:00:00.000: INFO: start", 0, 5, "fast"),
+		block("Apr  4 09:00:05.000: INFO: Pulling image", 5, 25, "slow"),
+		block("Apr  4 09:00:25.000: INFO: more", 25, 30, "fast"),
+		block("Apr  4 09:00:30.000: INFO: Waiting for build", 30, 150, "slow"),
+	)
+	expects := []BlockDelta{
+		{"fast", "", 10, 10, 0},
+		{"slow", "INFO: Pulling image", 0, 20, 20},
+		{"slow", "INFO: Waiting for build", 60, 120, 60},
+		{"slow", "INFO: Running 'oc delete'", 30, 0, -30},
+	}
+	deltas := blockDeltas(old, new)
+	if len(deltas) != len(expects) {
+		t.Fatalf("Expected %v block deltas, got %v", len(expects), deltas)
+	}
+	for i, e := range expects {
+		if deltas[i] != e {
+			t.Errorf("Expected %+v, got %+v", e, deltas[i])
+		}
+	}
+}
+
+func TestLoadTruncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stats.json")
+	if err := ioutil.WriteFile(path, []byte(`[{"name": "a.go:1", "time": 10, "block": []}]`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := Load(path, top.DefaultOptions()); err != nil || len(s) != 1 {
+		t.Errorf("Expected 1 test without summary.json, got %v, %v", s, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "summary.json"), []byte(`{"tests": 2}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, top.DefaultOptions()); err == nil || !strings.Contains(err.Error(), "1 of 2 tests") {
+		t.Errorf("Expected truncated stats error, got %v", err)
+	}
+}
+
+func TestDuplicateNames(t *testing.T) {
+	old := []top.Blocks{stats("unknown", 10), stats("unknown", 20)}
+	new := []top.Blocks{stats("unknown", 10), stats("unknown", 25)}
+	deltas := Compare(old, new)
+	if len(deltas) != 2 || deltas[0].Name != "unknown #2" || deltas[0].Delta != 5 {
+		t.Errorf("Expected repeated names matched in order, got %v", deltas)
+	}
+}
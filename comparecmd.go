@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/wozniakjan/test_eval/compare"
+	"github.com/wozniakjan/test_eval/top"
+)
+
+func compareBuilds(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	format := fs.String("format", "text", "Output format: text or json")
+	windowSize := fs.Int("w", 5, "Window size used for logs")
+	threshold := fs.Float64("t", 120, "Threshold in seconds used for logs")
+	detect := fs.String("detect", "window", "Detection of the slow parts used for logs: window or gap")
+	th := thresholdFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: compare [flags] OLD NEW\n\nOLD and NEW are stats.json files of analyze -c -1 or logs.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected OLD and NEW, got %v arguments", fs.NArg())
+	}
+
+	opts, err := th.options(top.Options{WindowSize: *windowSize, Threshold: top.Seconds(*threshold), Detection: top.Detection(*detect)})
+	if err != nil {
+		return err
+	}
+	old, err := compare.Load(fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+	new, err := compare.Load(fs.Arg(1), opts)
+	if err != nil {
+		return err
+	}
+	deltas := compare.Compare(old, new)
+	switch *format {
+	case "text":
+		return compare.WriteText(os.Stdout, deltas)
+	case "json":
+		return compare.WriteJSON(os.Stdout, deltas)
+	}
+	return fmt.Errorf("unknown format %q", *format)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/wozniakjan/test_eval/jenkins"
+)
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+func fetch(args []string) error {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	url := fs.String("jenkins", envOr("JENKINS", "https://ci.openshift.redhat.com/jenkins"), "Jenkins URL")
+	job := fs.String("job", envOr("JOB_NAME", "test_branch_origin_extended_builds"), "Job name")
+	build := fs.Int("build", 0, "Build ID, 0 to use -last or -n")
+	recent := fs.Int("n", 0, "Fetch 'n' most recent builds")
+	last := fs.String("last", "successful", "Build to fetch when neither -build nor -n is set: successful or completed")
+	logs := fs.String("logs", "logs", "Folder to cache the logs in")
+	fs.Parse(args)
+
+	c := jenkins.NewClient(*url)
+	ids, err := buildIDs(c, *job, *build, *recent, *last)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		path, cached, err := c.Fetch(*logs, *job, id)
+		if err != nil {
+			return err
+		}
+		if cached {
+			fmt.Fprintf(os.Stderr, "already exists %v\n", path)
+		} else {
+			fmt.Fprintf(os.Stderr, "fetched %v\n", path)
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
+
+func buildIDs(c *jenkins.Client, job string, build, recent int, last string) ([]int, error) {
+	if build > 0 {
+		return []int{build}, nil
+	}
+	if recent > 0 {
+		return c.Recent(job, recent)
+	}
+	var which string
+	switch last {
+	case "successful":
+		which = jenkins.LastSuccessful
+	case "completed":
+		which = jenkins.LastCompleted
+	default:
+		return nil, fmt.Errorf("invalid -last %q, expected successful or completed", last)
+	}
+	id, err := c.Last(job, which)
+	if err != nil {
+		return nil, err
+	}
+	return []int{id}, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+const (
+	ganttLabelWidth = 320
+	ganttPlotWidth  = 1200
+	ganttRowHeight  = 18
+	ganttAxisHeight = 30
+)
+
+var ganttTicks = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+var ganttColors = map[string]string{
+	top.Passed:  "rgba(128,200,128,0.8)",
+	top.Failed:  "rgba(220,80,80,0.8)",
+	top.Skipped: "rgba(180,180,180,0.8)",
+	top.Pending: "rgba(180,180,180,0.8)",
+}
+
+// ganttRow is a row of the timeline, a test or all the tests of a node.
+type ganttRow struct {
+	name  string
+	tests []top.Blocks
+}
+
+// span is a wall-clock interval.
+type span struct {
+	start, end time.Time
+}
+
+// ganttRows returns the tests with wall-clock times in rows sorted by start,
+// one per test or per node, and the number of tests without times.
+func ganttRows(stats []top.Blocks, byNode bool) ([]ganttRow, int) {
+	timed := make([]top.Blocks, 0, len(stats))
+	for _, s := range stats {
+		if !s.Start.IsZero() {
+			timed = append(timed, s)
+		}
+	}
+	sort.SliceStable(timed, func(i, j int) bool { return timed[i].Start.Before(timed[j].Start) })
+	rows := make([]ganttRow, 0)
+	if !byNode {
+		for _, s := range timed {
+			rows = append(rows, ganttRow{s.Name, []top.Blocks{s}})
+		}
+		return rows, len(stats) - len(timed)
+	}
+	index := make(map[int]int)
+	for _, s := range timed {
+		i, ok := index[s.Node]
+		if !ok {
+			i = len(rows)
+			index[s.Node] = i
+			rows = append(rows, ganttRow{fmt.Sprintf("node %v", s.Node), nil})
+		}
+		rows[i].tests = append(rows[i].tests, s)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].name < rows[j].name })
+	return rows, len(stats) - len(timed)
+}
+
+// idle returns the intervals between first and last when no test runs.
+func idle(stats []top.Blocks) []span {
+	busy := make([]span, 0, len(stats))
+	for _, s := range stats {
+		if !s.Start.IsZero() {
+			busy = append(busy, span{s.Start, s.End})
+		}
+	}
+	sort.Slice(busy, func(i, j int) bool { return busy[i].start.Before(busy[j].start) })
+	gaps := make([]span, 0)
+	if len(busy) == 0 {
+		return gaps
+	}
+	end := busy[0].end
+	for _, b := range busy[1:] {
+		if b.start.After(end) {
+			gaps = append(gaps, span{end, b.start})
+		}
+		if b.end.After(end) {
+			end = b.end
+		}
+	}
+	return gaps
+}
+
+func bounds(rows []ganttRow) (time.Time, time.Time) {
+	var first, last time.Time
+	for _, r := range rows {
+		for _, t := range r.tests {
+			if first.IsZero() || t.Start.Before(first) {
+				first = t.Start
+			}
+			if t.End.After(last) {
+				last = t.End
+			}
+		}
+	}
+	return first, last
+}
+
+func tickStep(d time.Duration) time.Duration {
+	for _, t := range ganttTicks {
+		if d/t <= 12 {
+			return t
+		}
+	}
+	return ganttTicks[len(ganttTicks)-1]
+}
+
+// writeGantt writes the rows as an SVG timeline on a shared wall-clock axis.
+// Idle time of the suite is shaded, gaps between the tests of a row are
+// dashed.
+func writeGantt(w io.Writer, rows []ganttRow, gaps []span) {
+	first, last := bounds(rows)
+	total := last.Sub(first)
+	if total <= 0 {
+		total = time.Second
+	}
+	x := func(t time.Time) float64 {
+		return ganttLabelWidth + float64(t.Sub(first))/float64(total)*ganttPlotWidth
+	}
+	width := func(s, e time.Time) float64 {
+		if w := x(e) - x(s); w > 1 {
+			return w
+		}
+		return 1
+	}
+	height := ganttAxisHeight + len(rows)*ganttRowHeight
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%v\" height=\"%v\" font-family=\"sans-serif\" font-size=\"11\">\n", ganttLabelWidth+ganttPlotWidth+20, height+5)
+	for _, g := range gaps {
+		fmt.Fprintf(w, "<rect x=\"%.1f\" y=\"0\" width=\"%.1f\" height=\"%v\" fill=\"rgba(255,200,0,0.25)\"><title>suite idle %v</title></rect>\n",
+			x(g.start), width(g.start, g.end), height, g.end.Sub(g.start))
+	}
+	step := tickStep(total)
+	for t := first.Truncate(step); !t.After(last); t = t.Add(step) {
+		if t.Before(first) {
+			continue
+		}
+		fmt.Fprintf(w, "<line x1=\"%.1f\" y1=\"%v\" x2=\"%.1f\" y2=\"%v\" stroke=\"#ddd\"/><text x=\"%.1f\" y=\"%v\" text-anchor=\"middle\">%v</text>\n",
+			x(t), ganttAxisHeight-5, x(t), height, x(t), ganttAxisHeight-10, t.UTC().Format("15:04:05"))
+	}
+	for i, r := range rows {
+		y := ganttAxisHeight + i*ganttRowHeight
+		fmt.Fprintf(w, "<text x=\"0\" y=\"%v\">%v</text>\n", y+ganttRowHeight-5, html.EscapeString(trunc(r.name)))
+		for j, t := range r.tests {
+			if j > 0 && t.Start.After(r.tests[j-1].End) {
+				prev := r.tests[j-1].End
+				fmt.Fprintf(w, "<rect x=\"%.1f\" y=\"%v\" width=\"%.1f\" height=\"%v\" fill=\"none\" stroke=\"#999\" stroke-dasharray=\"2,2\"><title>gap %v</title></rect>\n",
+					x(prev), y+6, width(prev, t.Start), ganttRowHeight-12, t.Start.Sub(prev))
+			}
+			color, ok := ganttColors[t.Status]
+			if !ok {
+				color = ganttColors[top.Passed]
+			}
+			fmt.Fprintf(w, "<rect x=\"%.1f\" y=\"%v\" width=\"%.1f\" height=\"%v\" fill=\"%v\"><title>%v\n%v - %v, %vs %v</title></rect>\n",
+				x(t.Start), y+2, width(t.Start, t.End), ganttRowHeight-4, color,
+				html.EscapeString(t.Name), t.Start.UTC().Format("15:04:05"), t.End.UTC().Format("15:04:05"), formatSeconds(t.Time), t.Status)
+			for _, b := range t.Blocks {
+				if b.BlockType != "slow" {
+					continue
+				}
+				s, e := t.Start.Add(top.Seconds(b.Start)), t.Start.Add(top.Seconds(b.End))
+				fmt.Fprintf(w, "<rect x=\"%.1f\" y=\"%v\" width=\"%.1f\" height=\"%v\" fill=\"rgba(150,0,0,0.6)\"><title>slow %vs</title></rect>\n",
+					x(s), y+6, width(s, e), ganttRowHeight-12, formatSeconds(b.End-b.Start))
+			}
+		}
+	}
+	fmt.Fprintf(w, "</svg>\n")
+}
+
+// renderGantt writes the timeline page, warning when total, the number of
+// tests in the suite, is more than the tests in stats.
+func renderGantt(out io.Writer, stats []top.Blocks, total int, byNode bool) error {
+	w := bufio.NewWriter(out)
+	rows, untimed := ganttRows(stats, byNode)
+	gaps := idle(stats)
+	first, last := bounds(rows)
+	var idleTime time.Duration
+	for _, g := range gaps {
+		idleTime += g.end.Sub(g.start)
+	}
+	fmt.Fprintf(w, "<!DOCTYPE HTML>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Test timeline</title>\n</head>\n<body style=\"font-family: sans-serif\">\n")
+	fmt.Fprintf(w, "<p>%v - %v UTC, %v wall-clock, %v idle", first.UTC().Format("2006-01-02 15:04:05"), last.UTC().Format("15:04:05"), last.Sub(first), idleTime)
+	if untimed > 0 {
+		fmt.Fprintf(w, ", %v tests without timestamps not shown", untimed)
+	}
+	fmt.Fprintf(w, "</p>\n")
+	if total > len(stats) {
+		fmt.Fprintf(w, "<p style=\"color: darkred\">Only %v of %v tests, the idle time may be the others, run analyze with -c -1 for all of them.</p>\n", len(stats), total)
+	}
+	writeGantt(w, rows, gaps)
+	fmt.Fprintf(w, "</body>\n</html>\n")
+	return w.Flush()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+func at(s int) time.Time {
+	return time.Date(2018, 4, 3, 12, 0, s, 0, time.UTC)
+}
+
+func TestGanttRows(t *testing.T) {
+	stats := []top.Blocks{
+		{Name: "b", Node: 2, Start: at(10), End: at(20)},
+		{Name: "a", Node: 1, Start: at(0), End: at(15)},
+		{Name: "untimed", Node: 1},
+		{Name: "c", Node: 1, Start: at(40), End: at(50)},
+	}
+	rows, untimed := ganttRows(stats, false)
+	if untimed != 1 || len(rows) != 3 || rows[0].name != "a" || rows[1].name != "b" || rows[2].name != "c" {
+		t.Errorf("Expected rows a, b, c and 1 untimed test, got %v %v", rows, untimed)
+	}
+	rows, _ = ganttRows(stats, true)
+	if len(rows) != 2 || rows[0].name != "node 1" || len(rows[0].tests) != 2 || rows[0].tests[1].Name != "c" || rows[1].name != "node 2" {
+		t.Errorf("Expected node rows, got %v", rows)
+	}
+	gaps := idle(stats)
+	if len(gaps) != 1 || !gaps[0].start.Equal(at(20)) || !gaps[0].end.Equal(at(40)) {
+		t.Errorf("Expected idle from 20s to 40s, got %v", gaps)
+	}
+	buf := &bytes.Buffer{}
+	writeGantt(buf, rows, gaps)
+	for _, s := range []string{"<title>suite idle 20s</title>", "<title>gap 25s</title>", ">12:00:00</text>"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Expected %q in %v", s, buf.String())
+		}
+	}
+}
+
+func TestRenderGanttTruncated(t *testing.T) {
+	stats := []top.Blocks{{Name: "a", Start: at(0), End: at(10)}, {Name: "b", Start: at(20), End: at(30)}}
+	for _, tc := range []struct {
+		total int
+		warn  bool
+	}{
+		{0, false},
+		{2, false},
+		{5, true},
+	} {
+		buf := &bytes.Buffer{}
+		if err := renderGantt(buf, stats, tc.total, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if warn := strings.Contains(buf.String(), "Only 2 of 5 tests"); warn != tc.warn {
+			t.Errorf("Expected truncation warning %v for %v tests, got %v", tc.warn, tc.total, warn)
+		}
+	}
+}
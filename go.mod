@@ -0,0 +1,3 @@
+module github.com/wozniakjan/test_eval
+
+go 1.24
@@ -2,56 +2,89 @@ package main
 
 import (
 	"bufio"
+	_ "embed"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"os"
+	"math"
+	"path/filepath"
+	"strconv"
 	"strings"
+
+	"github.com/wozniakjan/test_eval/top"
 )
 
-var out = flag.String("o", "out_graph.html", "output html")
-var in = flag.String("i", "stats.json", "list of input stats.json")
+// chartJS is inlined into the page so it works offline and when moved.
+//
+//go:embed Chart.bundle.js
+var chartJS string
 
+// dataSet is a chart.js dataset, the blocks at the same position in all the
+// tests. Labels are the lines of each block, last line first.
 type dataSet struct {
-	labels          []string
-	backgroundColor string
-	data            []string
-	stack           string
-}
-type test struct {
-	offset int64
-	Name   string  `json:"name"`
-	Blocks []block `json:"block"`
+	Labels          [][]string    `json:"labels"`
+	BackgroundColor string        `json:"backgroundColor"`
+	Data            []json.Number `json:"data"`
+	Stack           string        `json:"stack"`
 }
-type block struct {
-	Lines     []string `json:"lines"`
-	Start     int64    `json:"start"`
-	End       int64    `json:"end"`
-	BlockType string   `json:"blockType"`
+
+func graph(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	out := fs.String("o", "out_graph.html", "output html")
+	in := fs.String("i", "stats.json", "list of input stats.json")
+	external := fs.Bool("external", false, "Load ./Chart.bundle.js next to the output instead of embedding it")
+	view := fs.String("view", "chart", "Page to generate: chart, report with a sortable test table, filters and the test output, or timeline with the wall-clock times")
+	byNode := fs.Bool("nodes", false, "One timeline row per ginkgo node instead of per test")
+	fs.Parse(args)
+	data, err := readInput(*in)
+	if err != nil {
+		return err
+	}
+	switch *view {
+	case "chart":
+		return writeFile(*out, func(w io.Writer) error { return renderPage(w, data, !*external) })
+	case "report":
+		return writeFile(*out, func(w io.Writer) error { return renderReport(w, reportTests(filepath.Dir(*in), data)) })
+	case "timeline":
+		// without summary.json the suite size is unknown
+		s, _ := top.ReadSummary(filepath.Join(filepath.Dir(*in), "summary.json"))
+		return writeFile(*out, func(w io.Writer) error { return renderGantt(w, data, s.Tests, *byNode) })
+	}
+	return fmt.Errorf("unknown view %q", *view)
 }
 
-func readInput() []test {
-	b := make([]test, 0)
-	input, e := ioutil.ReadFile(*in)
-	if e != nil {
-		panic(e)
+func readInput(in string) ([]top.Blocks, error) {
+	b := make([]top.Blocks, 0)
+	input, err := ioutil.ReadFile(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(input, &b); err != nil {
+		return nil, fmt.Errorf("%v: %v", in, err)
 	}
-	json.Unmarshal(input, &b)
-	return b //[0:3]
+	return b, nil
 }
 
-func testNames(b []test) string {
+// testNames returns the chart labels as json, the test names with the status
+// of the tests that did not pass.
+func testNames(b []top.Blocks) string {
 	labels := make([]string, 0)
 	for _, l := range b {
-		labels = append(labels, l.Name)
+		if l.Status != "" && l.Status != top.Passed {
+			labels = append(labels, l.Name+" ("+l.Status+")")
+		} else {
+			labels = append(labels, l.Name)
+		}
 	}
-	return "[\"" + strings.Join(labels, "\",\n \"") + "\"],"
+	names, _ := json.Marshal(labels)
+	return string(names)
 }
 
-func max(tests []test) (int, int64) {
+func max(tests []top.Blocks) (int, float64) {
 	var max int
-	var maxTime int64
+	var maxTime float64
 	for _, t := range tests {
 		if max < len(t.Blocks) {
 			max = len(t.Blocks)
@@ -64,16 +97,16 @@ func max(tests []test) (int, int64) {
 	return max, maxTime
 }
 
-func toDataSets(tests []test) []dataSet {
+func toDataSets(tests []top.Blocks) []dataSet {
 	max, _ := max(tests)
 	ds := make([]dataSet, max)
 	color := "rgba(128,200,128,0.7)"
 	for i := 0; i < max; i++ {
-		labels := make([]string, len(tests))
+		labels := make([][]string, len(tests))
 		for j := 0; j < len(tests); j++ {
-			labels[j] = "[]"
+			labels[j] = []string{}
 		}
-		values := make([]string, len(tests))
+		values := make([]json.Number, len(tests))
 		for j := 0; j < len(tests); j++ {
 			values[j] = "0"
 		}
@@ -89,18 +122,23 @@ func toDataSets(tests []test) []dataSet {
 		for ib, b := range bs.Blocks {
 			labels := make([]string, 0)
 			for _, l := range b.Lines {
-				labels = append(labels, fmt.Sprintf("%q", trunc(l)))
+				labels = append(labels, trunc(l))
 			}
 			for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
 				labels[i], labels[j] = labels[j], labels[i]
 			}
-			ds[ib].labels[i] = `[` + strings.Join(labels, ", ") + `]`
-			ds[ib].data[i] = fmt.Sprintf("%v", b.End-b.Start)
+			ds[ib].Labels[i] = labels
+			ds[ib].Data[i] = json.Number(formatSeconds(b.End - b.Start))
 		}
 	}
 	return ds
 }
 
+// formatSeconds formats a duration in seconds rounded to milliseconds.
+func formatSeconds(s float64) string {
+	return strconv.FormatFloat(math.Round(s*1000)/1000, 'f', -1, 64)
+}
+
 func trunc(a string) string {
 	if len(a) > 100 {
 		return a[0:97] + `...`
@@ -108,42 +146,35 @@ func trunc(a string) string {
 	return a
 }
 
-func dataSets(test []test) string {
-	ds := toDataSets(test)
-	strs := make([]string, len(ds))
-	for i, d := range ds {
-		labels := strings.Join(d.labels, ",\n")
-		data := strings.Join(d.data, ",\n")
-		strs[i] = fmt.Sprintf(
-			"{ labels: [%v],\nbackgroundColor: \"%v\",\ndata: [%v], stack: 1 }\n",
-			labels,
-			d.backgroundColor,
-			data)
+// dataSets returns the datasets as json, which escapes <, > and & so the
+// lines can not end the script.
+func dataSets(test []top.Blocks) string {
+	ds, _ := json.Marshal(toDataSets(test))
+	return string(ds)
+}
+
+// chartScript returns the script tag with the embedded chart library, or
+// loading it from the output folder.
+func chartScript(embedded bool) string {
+	if !embedded {
+		return `<script src="./Chart.bundle.js"></script>`
 	}
-	return strings.Join(strs, ", ")
+	return "<script>\n" + strings.Replace(chartJS, "</script", `<\/script`, -1) + "\n</script>"
 }
 
-func renderPage(b []test) {
-	f, _ := os.Create(*out)
-	defer f.Close()
-	w := bufio.NewWriter(f)
-	defer w.Flush()
+func renderPage(out io.Writer, b []top.Blocks, embedded bool) error {
+	w := bufio.NewWriter(out)
 	data := `
 				var data = {
-					labels: ` + testNames(b) + `
-					datasets: [ ` + dataSets(b) + `]
+					labels: ` + testNames(b) + `,
+					datasets: ` + dataSets(b) + `
 				};
 `
-	fmt.Fprintf(w, pre)
-	fmt.Fprintf(w, data)
+	fmt.Fprint(w, pre)
+	fmt.Fprint(w, data)
 	_, maxTime := max(b)
-	fmt.Fprintf(w, post(fmt.Sprintf("%v", int64(float64(maxTime)*1.02))))
-}
-
-func main() {
-	flag.Parse()
-	data := readInput()
-	renderPage(data)
+	fmt.Fprint(w, post(fmt.Sprintf("%v", math.Ceil(maxTime*1.02)), chartScript(embedded)))
+	return w.Flush()
 }
 
 var pre = `
@@ -226,7 +257,7 @@ var pre = `
 
 `
 
-func post(max string) string {
+func post(max, script string) string {
 	return `
 				var ctx = document.getElementById("myChart").getContext("2d");
 				new Chart(ctx, {
@@ -273,6 +304,10 @@ func post(max string) string {
                                     return;
                                 }
 
+                                function escapeHtml(s) {
+                                    return String(s).replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
+                                }
+
                                 function getBody(bodyItem) {
                                     return bodyItem.lines;
                                 }
@@ -284,13 +319,13 @@ func post(max string) string {
                                     var innerHtml = '<thead>';
 
                                     titleLines.forEach(function(title) {
-                                        var link = title.replace(/:/i,"#L");
-                                        innerHtml += '<tr><th align="left">' + '<a href="https://github.com/openshift/origin/tree/master'+link+'" style="color:white; text-decoration: none">' + title + '</a>' + '</th></tr>';
+                                        var link = encodeURI(title.replace(/:/i,"#L"));
+                                        innerHtml += '<tr><th align="left">' + '<a href="https://github.com/openshift/origin/tree/master'+escapeHtml(link)+'" style="color:white; text-decoration: none">' + escapeHtml(title) + '</a>' + '</th></tr>';
                                     });
                                     innerHtml += '</thead><tbody>';
 
                                     bodyLines.forEach(function(body, i) {
-                                        innerHtml += '<tr><td>' + body + '</td></tr>';
+                                        innerHtml += '<tr><td>' + escapeHtml(body) + '</td></tr>';
                                     });
                                     innerHtml += '</tbody>';
 
@@ -320,7 +355,7 @@ func post(max string) string {
 	</head>
 	<body>
         <canvas id="myChart"></canvas>
-		<script src="./Chart.bundle.js"></script>
+		` + script + `
 	</body>
 </html>
 `
@@ -1,111 +1,117 @@
 package main
 
 import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/wozniakjan/test_eval/top"
 )
 
 func TestToDs(t *testing.T) {
-	tests := []test{
-		test{
-			0,
-			"test1",
-			[]block{
-				block{
-					[]string{"1line1", "1line2"},
-					0,
-					7,
-					"fast",
+	tests := []top.Blocks{
+		top.Blocks{
+			Name: "test1",
+			Blocks: []top.Block{
+				top.Block{
+					Lines:     []string{"1line1", "1line2"},
+					Start:     0,
+					End:       7,
+					BlockType: "fast",
 				},
-				block{
-					[]string{"1line3", "1line4"},
-					7,
-					17,
-					"slow",
+				top.Block{
+					Lines:     []string{"1line3", "1line4"},
+					Start:     7,
+					End:       17,
+					BlockType: "slow",
 				},
-				block{
-					[]string{"1line5", "1line6"},
-					17,
-					18,
-					"fast",
+				top.Block{
+					Lines:     []string{"1line5", "1line6"},
+					Start:     17,
+					End:       18,
+					BlockType: "fast",
 				},
 			},
 		},
-		test{
-			0,
-			"test2",
-			[]block{
-				block{
-					[]string{"2line1", "2line2"},
-					0,
-					1,
-					"fast",
+		top.Blocks{
+			Name: "test2",
+			Blocks: []top.Block{
+				top.Block{
+					Lines:     []string{"2line1", "2line2"},
+					Start:     0,
+					End:       1,
+					BlockType: "fast",
 				},
-				block{
-					[]string{"2line3", "2line4"},
-					1,
-					4,
-					"slow",
+				top.Block{
+					Lines:     []string{"2line3", "2line4"},
+					Start:     1,
+					End:       4,
+					BlockType: "slow",
 				},
-				block{
-					[]string{"2line5", "2line6"},
-					4,
-					6,
-					"fast",
+				top.Block{
+					Lines:     []string{"2line5", "2line6"},
+					Start:     4,
+					End:       6,
+					BlockType: "fast",
 				},
-				block{
-					[]string{"2line7", "2line8"},
-					6,
-					11,
-					"slow",
+				top.Block{
+					Lines:     []string{"2line7", "2line8"},
+					Start:     6,
+					End:       11,
+					BlockType: "slow",
 				},
 			},
 		},
 	}
 	expects := []dataSet{
 		dataSet{
-			[]string{
-				"[\"1line2\", \"1line1\"]",
-				"[\"2line2\", \"2line1\"]",
+			[][]string{
+				{"1line2", "1line1"},
+				{"2line2", "2line1"},
 			},
 			"rgba(128,200,128,0.7)",
-			[]string{
+			[]json.Number{
 				"7",
 				"1",
 			},
 			"1",
 		},
 		dataSet{
-			[]string{
-				"[\"1line4\", \"1line3\"]",
-				"[\"2line4\", \"2line3\"]",
+			[][]string{
+				{"1line4", "1line3"},
+				{"2line4", "2line3"},
 			},
 			"rgba(200,128,128,0.7)",
-			[]string{
+			[]json.Number{
 				"10",
 				"3",
 			},
 			"1",
 		},
 		dataSet{
-			[]string{
-				"[\"1line6\", \"1line5\"]",
-				"[\"2line6\", \"2line5\"]",
+			[][]string{
+				{"1line6", "1line5"},
+				{"2line6", "2line5"},
 			},
 			"rgba(128,200,128,0.7)",
-			[]string{
+			[]json.Number{
 				"1",
 				"2",
 			},
 			"1",
 		},
 		dataSet{
-			[]string{
-				"[]",
-				"[\"2line8\", \"2line7\"]",
+			[][]string{
+				{},
+				{"2line8", "2line7"},
 			},
 			"rgba(200,128,128,0.7)",
-			[]string{
+			[]json.Number{
 				"0",
 				"5",
 			},
@@ -121,14 +127,89 @@ func TestToDs(t *testing.T) {
 			t.Errorf("unexpected: %v", ds)
 			continue
 		}
-		if !reflect.DeepEqual(expects[i].labels, ds.labels) {
-			t.Errorf("Labels Expected: %v, got %v", expects[i].labels, ds.labels)
+		if !reflect.DeepEqual(expects[i].Labels, ds.Labels) {
+			t.Errorf("Labels Expected: %v, got %v", expects[i].Labels, ds.Labels)
+		}
+		if !reflect.DeepEqual(expects[i].Data, ds.Data) {
+			t.Errorf("Data Expected: %v, got %v", expects[i].Data, ds.Data)
+		}
+		if !reflect.DeepEqual(expects[i].BackgroundColor, ds.BackgroundColor) {
+			t.Errorf("Color Expected: %v, got %v", expects[i].BackgroundColor, ds.BackgroundColor)
+		}
+	}
+}
+
+func TestFormatSeconds(t *testing.T) {
+	for _, tc := range []struct {
+		in     float64
+		expect string
+	}{
+		{7, "7"},
+		{0.1 + 0.2, "0.3"},
+		{546.0571, "546.057"},
+	} {
+		if got := formatSeconds(tc.in); got != tc.expect {
+			t.Errorf("Expected %v, got %v", tc.expect, got)
+		}
+	}
+}
+
+func TestRenderPageEmbedded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stats := []top.Blocks{{Name: "test.go:1", Blocks: []top.Block{{Start: 0, End: 7, BlockType: "fast"}}}}
+	for _, tc := range []struct {
+		embedded bool
+		src      bool
+	}{
+		{true, false},
+		{false, true},
+	} {
+		out := filepath.Join(dir, "graph.html")
+		if err := writeFile(out, func(w io.Writer) error { return renderPage(w, stats, tc.embedded) }); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		page, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
 		}
-		if !reflect.DeepEqual(expects[i].data, ds.data) {
-			t.Errorf("Data Expected: %v, got %v", expects[i].data, ds.data)
+		if src := strings.Contains(string(page), `src="./Chart.bundle.js"`); src != tc.src {
+			t.Errorf("Expected external script %v, got %v", tc.src, src)
 		}
-		if !reflect.DeepEqual(expects[i].backgroundColor, ds.backgroundColor) {
-			t.Errorf("Color Expected: %v, got %v", expects[i].backgroundColor, ds.backgroundColor)
+		if inlined := strings.Contains(string(page), chartJS); inlined != tc.embedded {
+			t.Errorf("Expected embedded chart library %v, got %v", tc.embedded, inlined)
 		}
 	}
 }
+
+func TestRenderPageEscapes(t *testing.T) {
+	stats := []top.Blocks{{Name: "test.go:1", Blocks: []top.Block{{Lines: []string{`</script><script>alert(1)</script>`}, Start: 0, End: 7, BlockType: "fast"}}}}
+	var page strings.Builder
+	if err := renderPage(&page, stats, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(page.String(), "<script>alert(1)") || !strings.Contains(page.String(), `\u003c/script\u003e\u003cscript\u003ealert(1)`) {
+		t.Errorf("Expected the escaped block lines, got %v", page.String())
+	}
+}
+
+func TestRenderPageQuotedNames(t *testing.T) {
+	name := `[sig-builds] "quoted" \ spec`
+	stats := []top.Blocks{{Name: name, Status: top.Failed, Blocks: []top.Block{{Start: 0, End: 7, BlockType: "fast"}}}}
+	var page strings.Builder
+	if err := renderPage(&page, stats, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	i := strings.Index(page.String(), "labels: ")
+	if i < 0 {
+		t.Fatalf("Expected the chart labels, got %v", page.String())
+	}
+	line := strings.TrimSuffix(strings.SplitN(page.String()[i+len("labels: "):], "\n", 2)[0], ",")
+	labels := make([]string, 0)
+	if err := json.Unmarshal([]byte(line), &labels); err != nil || len(labels) != 1 || labels[0] != name+" (failed)" {
+		t.Errorf("Expected the label %q, got %v, %v", name+" (failed)", line, err)
+	}
+}
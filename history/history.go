@@ -0,0 +1,290 @@
+// Package history stores analyzed builds in a directory and answers questions
+// about the test durations over several builds.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+// Build is a single analyzed build, stored as <dir>/<job>/<id>.json.
+type Build struct {
+	Job     string    `json:"job"`
+	ID      int       `json:"id"`
+	Started time.Time `json:"started"`
+	Tests   []Test    `json:"tests"`
+}
+
+// Test is the duration of a test in seconds with its slow windows and
+// phases, e.g. docker builds.
+type Test struct {
+	Name    string   `json:"name"`
+	Time    float64  `json:"time"`
+	Windows []Window `json:"windows,omitempty"`
+	Phases  []Phase  `json:"phases,omitempty"`
+}
+
+// Window is a slow window of a test.
+type Window struct {
+	Time  float64  `json:"time"`
+	Lines []string `json:"lines"`
+}
+
+// Phase is a phase of a test, e.g. a docker build.
+type Phase struct {
+	Name string  `json:"name"`
+	Time float64 `json:"time"`
+}
+
+// NewBuild converts a parsed report into a build record.
+func NewBuild(job string, id int, started time.Time, r *top.Report) Build {
+	b := Build{job, id, started, make([]Test, 0, len(r.Tests))}
+	for _, t := range r.Tests {
+		ht := Test{Name: t.Name(), Time: t.Time}
+		for _, w := range t.Windows {
+			lines := make([]string, 0, len(w.TimedWindow))
+			for _, l := range w.TimedWindow {
+				lines = append(lines, l.Line)
+			}
+			ht.Windows = append(ht.Windows, Window{w.Time().Seconds(), lines})
+		}
+		for _, p := range t.Phases.Phases {
+			ht.Phases = append(ht.Phases, Phase{p.Name, p.Duration().Seconds()})
+		}
+		b.Tests = append(b.Tests, ht)
+	}
+	return b
+}
+
+// Store is a directory with the build records.
+type Store struct {
+	Dir string
+}
+
+// ValidName rejects the names escaping the folders, e.g. the job folders of
+// a store.
+func ValidName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+func validJob(job string) error {
+	if !ValidName(job) {
+		return fmt.Errorf("invalid job name %q", job)
+	}
+	return nil
+}
+
+func (s Store) file(job string, id int) string {
+	return filepath.Join(s.Dir, job, fmt.Sprintf("%v.json", id))
+}
+
+// Add stores the build, replacing the previous record of the same build.
+func (s Store) Add(b Build) error {
+	if err := validJob(b.Job); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(s.Dir, b.Job), 0777); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.file(b.Job, b.ID) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.file(b.Job, b.ID))
+}
+
+// Jobs returns the names of the stored jobs.
+func (s Store) Jobs() ([]string, error) {
+	files, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	jobs := make([]string, 0)
+	for _, f := range files {
+		if f.IsDir() {
+			jobs = append(jobs, f.Name())
+		}
+	}
+	return jobs, nil
+}
+
+// Builds returns the IDs of the stored builds of a job, newest first.
+func (s Store) Builds(job string) ([]int, error) {
+	if err := validJob(job); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(filepath.Join(s.Dir, job))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ids := make([]int, 0)
+	for _, f := range files {
+		if id, err := strconv.Atoi(strings.TrimSuffix(f.Name(), ".json")); err == nil && strings.HasSuffix(f.Name(), ".json") {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	return ids, nil
+}
+
+// Get reads a stored build.
+func (s Store) Get(job string, id int) (*Build, error) {
+	if err := validJob(job); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(s.file(job, id))
+	if err != nil {
+		return nil, err
+	}
+	b := &Build{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("%v: %v", s.file(job, id), err)
+	}
+	return b, nil
+}
+
+// Last returns at most n most recent builds of a job, oldest first. All the
+// builds are returned when n is less than 1.
+func (s Store) Last(job string, n int) ([]Build, error) {
+	ids, err := s.Builds(job)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 && n < len(ids) {
+		ids = ids[:n]
+	}
+	builds := make([]Build, len(ids))
+	for i, id := range ids {
+		b, err := s.Get(job, id)
+		if err != nil {
+			return nil, err
+		}
+		builds[len(ids)-1-i] = *b
+	}
+	return builds, nil
+}
+
+// Summary describes the durations of a test in seconds.
+type Summary struct {
+	Name   string  `json:"name"`
+	Builds int     `json:"builds"`
+	P50    float64 `json:"p50"`
+	P95    float64 `json:"p95"`
+	Max    float64 `json:"max"`
+}
+
+// percentile uses the nearest rank method on sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// durations returns the durations of the test in the builds, a build running
+// the test several times contributes all of them.
+func durations(builds []Build, test string) []float64 {
+	d := make([]float64, 0, len(builds))
+	for _, b := range builds {
+		for _, t := range b.Tests {
+			if t.Name == test {
+				d = append(d, t.Time)
+			}
+		}
+	}
+	return d
+}
+
+// Summarize returns the p50, p95 and max duration of a test over the last n
+// builds of a job.
+func (s Store) Summarize(job, test string, n int) (Summary, error) {
+	builds, err := s.Last(job, n)
+	if err != nil {
+		return Summary{}, err
+	}
+	d := durations(builds, test)
+	if len(d) == 0 {
+		return Summary{}, fmt.Errorf("test %v not found in the last %v builds of %v", test, len(builds), job)
+	}
+	sort.Float64s(d)
+	return Summary{test, len(d), percentile(d, 50), percentile(d, 95), d[len(d)-1]}, nil
+}
+
+// Trend is the change of a test duration in seconds per build.
+type Trend struct {
+	Name   string  `json:"name"`
+	Builds int     `json:"builds"`
+	Slope  float64 `json:"slope"`
+	First  float64 `json:"first"`
+	Last   float64 `json:"last"`
+}
+
+// slope fits a line through the durations with least squares.
+func slope(d []float64) float64 {
+	n := float64(len(d))
+	var sx, sy, sxy, sxx float64
+	for i, y := range d {
+		x := float64(i)
+		sx += x
+		sy += y
+		sxy += x * y
+		sxx += x * x
+	}
+	return (n*sxy - sx*sy) / (n*sxx - sx*sx)
+}
+
+// Rising returns the tests whose duration grows over the last n builds of a
+// job by at least minSlope seconds per build, the steepest first. Tests found
+// in less than 3 builds are skipped.
+func (s Store) Rising(job string, n int, minSlope float64) ([]Trend, error) {
+	builds, err := s.Last(job, n)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, b := range builds {
+		for _, t := range b.Tests {
+			if !seen[t.Name] {
+				seen[t.Name] = true
+				names = append(names, t.Name)
+			}
+		}
+	}
+	trends := make([]Trend, 0)
+	for _, name := range names {
+		d := durations(builds, name)
+		if len(d) < 3 {
+			continue
+		}
+		if sl := slope(d); sl > 0 && sl >= minSlope {
+			trends = append(trends, Trend{name, len(d), sl, d[0], d[len(d)-1]})
+		}
+	}
+	sort.SliceStable(trends, func(i, j int) bool { return trends[i].Slope > trends[j].Slope })
+	return trends, nil
+}
@@ -0,0 +1,93 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func store(t *testing.T, durations ...[]float64) (Store, func()) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Store{dir}
+	for i, d := range durations {
+		b := Build{Job: "builds", ID: 420 + i, Started: time.Date(2018, 4, 3+i, 0, 0, 0, 0, time.UTC)}
+		b.Tests = []Test{{Name: "pipeline.go:437", Time: d[0]}, {Name: "digest.go:65", Time: d[1]}}
+		if err := s.Add(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestSummarize(t *testing.T) {
+	s, cleanup := store(t,
+		[]float64{500, 60},
+		[]float64{100, 61},
+		[]float64{300, 59},
+		[]float64{200, 60},
+		[]float64{400, 60},
+	)
+	defer cleanup()
+
+	ids, _ := s.Builds("builds")
+	if !reflect.DeepEqual(ids, []int{424, 423, 422, 421, 420}) {
+		t.Errorf("Expected builds 424..420, got %v", ids)
+	}
+	sum, err := s.Summarize("builds", "pipeline.go:437", 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect := Summary{"pipeline.go:437", 4, 200, 400, 400}
+	if sum != expect {
+		t.Errorf("Expected %v, got %v", expect, sum)
+	}
+	if _, err := s.Summarize("builds", "missing.go:1", 4); err == nil {
+		t.Errorf("Expected error for missing test")
+	}
+}
+
+func TestRising(t *testing.T) {
+	s, cleanup := store(t,
+		[]float64{100, 60},
+		[]float64{110, 61},
+		[]float64{130, 59},
+		[]float64{160, 60},
+	)
+	defer cleanup()
+
+	trends, err := s.Rising("builds", 0, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(trends) != 1 || trends[0].Name != "pipeline.go:437" || trends[0].Slope != 20 || trends[0].First != 100 || trends[0].Last != 160 {
+		t.Errorf("Expected pipeline.go:437 rising 20s per build, got %v", trends)
+	}
+	if trends, _ := s.Rising("builds", 2, 0); len(trends) != 0 {
+		t.Errorf("Expected no trend from 2 builds, got %v", trends)
+	}
+}
+
+func TestInvalidJob(t *testing.T) {
+	s, cleanup := store(t, []float64{100, 60})
+	defer cleanup()
+
+	for _, job := range []string{"", ".", "..", "../builds", `a\b`} {
+		if err := s.Add(Build{Job: job, ID: 1}); err == nil {
+			t.Errorf("Expected error adding job %q", job)
+		}
+		if _, err := s.Builds(job); err == nil {
+			t.Errorf("Expected error listing job %q", job)
+		}
+		if _, err := s.Get(job, 420); err == nil {
+			t.Errorf("Expected error reading job %q", job)
+		}
+		if _, err := s.Last(job, 0); err == nil {
+			t.Errorf("Expected error reading last builds of job %q", job)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/wozniakjan/test_eval/history"
+	"github.com/wozniakjan/test_eval/top"
+)
+
+func historyUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: history <ingest|stats|rising> [flags]\n\n")
+	fmt.Fprintf(os.Stderr, "  ingest  analyze a log and add it to the history\n")
+	fmt.Fprintf(os.Stderr, "  stats   p50, p95 and max duration of a test over the last builds\n")
+	fmt.Fprintf(os.Stderr, "  rising  tests getting slower over the last builds\n")
+}
+
+func historyCmd(args []string) error {
+	if len(args) < 1 {
+		historyUsage()
+		return fmt.Errorf("missing history command")
+	}
+	fs := flag.NewFlagSet("history "+args[0], flag.ExitOnError)
+	dir := fs.String("dir", "history", "History folder")
+	job := fs.String("job", "", "Job name, taken from the log name for ingest when empty")
+	format := fs.String("format", "text", "Output format: text or json")
+	n := fs.Int("n", 10, "Use 'n' most recent builds, all when less than 1")
+	parse := func() history.Store {
+		fs.Parse(args[1:])
+		return history.Store{Dir: *dir}
+	}
+	switch args[0] {
+	case "ingest":
+		in := inputFlags(fs)
+		build := fs.Int("build", 0, "Build ID, taken from the log name when 0")
+		windowSize := fs.Int("w", 5, "Window size")
+		threshold := fs.Float64("t", 120, "Threshold in seconds to identify windows/bottleneck")
+		detect := fs.String("detect", "window", "Detection of the slow parts: window, the -w timestamped lines taking more than -t, or gap, more than -t between two timestamped lines")
+		th := thresholdFlags(fs)
+		store := parse()
+		*job, *build = logBuild(*in.file, *job, *build)
+		if *job == "" || *build == 0 {
+			return fmt.Errorf("-job and -build are required for %v", *in.file)
+		}
+		opts, err := th.options(top.Options{WindowSize: *windowSize, Threshold: top.Seconds(*threshold), Detection: top.Detection(*detect)})
+		if err != nil {
+			return err
+		}
+		report, err := in.load(opts)
+		if err != nil {
+			return err
+		}
+		started, err := buildStart(*in.file, *in.start)
+		if err != nil {
+			return err
+		}
+		return store.Add(history.NewBuild(*job, *build, started, report))
+	case "stats":
+		test := fs.String("test", "", "Test name, e.g. /test/extended/builds/pipeline.go:437")
+		store := parse()
+		sum, err := store.Summarize(*job, *test, *n)
+		if err != nil {
+			return err
+		}
+		if *format == "json" {
+			return printJSON(sum)
+		}
+		fmt.Printf("%v: %v builds, p50 %vs, p95 %vs, max %vs\n", sum.Name, sum.Builds, sum.P50, sum.P95, sum.Max)
+		return nil
+	case "rising":
+		minSlope := fs.Float64("min", 1, "Minimal growth in seconds per build")
+		store := parse()
+		trends, err := store.Rising(*job, *n, *minSlope)
+		if err != nil {
+			return err
+		}
+		if *format == "json" {
+			return printJSON(trends)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(tw, "SLOPE\tFIRST\tLAST\tBUILDS\tTEST\n")
+		for _, t := range trends {
+			fmt.Fprintf(tw, "%.3fs/build\t%vs\t%vs\t%v\t%v\n", t.Slope, t.First, t.Last, t.Builds, t.Name)
+		}
+		return tw.Flush()
+	}
+	historyUsage()
+	return fmt.Errorf("unknown history command %q", args[0])
+}
+
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Printf("%s\n", data)
+	return err
+}
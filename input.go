@@ -0,0 +1,253 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/wozniakjan/test_eval/jenkins"
+	"github.com/wozniakjan/test_eval/top"
+)
+
+// inputs are the files to analyze, shared by the commands parsing logs.
+type inputs struct {
+	file   *string
+	demux  *bool
+	nodes  *string
+	junit  *string
+	ginkgo *string
+	start  *string
+	phases *string
+}
+
+func inputFlags(fs *flag.FlagSet) inputs {
+	return inputs{
+		fs.String("f", "", "Log file to parse"),
+		fs.Bool("demux", false, "Split the -f log into parallel ginkgo nodes by the [N] line prefixes"),
+		fs.String("nodes", "", "Log files of the parallel ginkgo nodes to parse instead of -f, e.g. 'logs/node-*.log'"),
+		fs.String("junit", "", "JUnit XML files to parse, e.g. 'artifacts/junit_*.xml', linked to the tests of -f or -nodes if set"),
+		fs.String("ginkgo", "", "Ginkgo JSON report to parse instead of the log"),
+		fs.String("start", "", "Start of the build in RFC3339, read from the fetched build metadata when empty"),
+		fs.String("phases", "", "JSON file with the phase rules, docker build and push when empty"),
+	}
+}
+
+// logNameRegexp matches the logs cached by fetch, <build>-<job>.log
+var logNameRegexp = regexp.MustCompile(`^([0-9]+)-(.+)\.log$`)
+
+// logBuild returns the job and build, taken from the name of a log cached by
+// fetch when not set.
+func logBuild(file, job string, build int) (string, int) {
+	if m := logNameRegexp.FindStringSubmatch(filepath.Base(file)); len(m) > 2 {
+		if build == 0 {
+			build, _ = strconv.Atoi(m[1])
+		}
+		if job == "" {
+			job = m[2]
+		}
+	}
+	return job, build
+}
+
+// buildStart parses start in RFC3339, or reads the build start from the build
+// metadata saved next to the log by fetch.
+func buildStart(file, start string) (time.Time, error) {
+	if start != "" {
+		return time.Parse(time.RFC3339, start)
+	}
+	if b, err := jenkins.ReadBuild(file); err == nil {
+		return b.Started(), nil
+	}
+	return time.Time{}, nil
+}
+
+func warnJumps(r *top.Report) {
+	for _, j := range r.Jumps {
+		fmt.Fprintf(os.Stderr, "time goes back %v: %v\n", j.From.Sub(j.To), j.Line)
+	}
+}
+
+func parseLog(file string, demux bool, opts top.Options) (*top.Report, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	parse := top.Parse
+	if demux {
+		parse = top.ParseStream
+	}
+	report, err := parse(f, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", file, err)
+	}
+	return report, nil
+}
+
+func glob(pattern string) ([]string, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %v", pattern)
+	}
+	return files, nil
+}
+
+// parseNodes parses the log files of the parallel nodes, in the order of
+// their names.
+func parseNodes(pattern string, opts top.Options) (*top.Report, error) {
+	files, err := glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	readers := make([]io.Reader, 0, len(files))
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	return top.ParseNodes(readers, opts)
+}
+
+// parseJUnit parses all the JUnit files matching the pattern into one report.
+func parseJUnit(pattern string, opts top.Options) (*top.Report, error) {
+	files, err := glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	readers := make([]io.Reader, 0, len(files))
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	report, err := top.ParseJUnits(readers, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", pattern, err)
+	}
+	return report, nil
+}
+
+// parseGinkgo parses a ginkgo JSON report.
+func parseGinkgo(file string, opts top.Options) (*top.Report, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	report, err := top.ParseGinkgo(f, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", file, err)
+	}
+	return report, nil
+}
+
+// thresholds are the threshold strategy flags shared by the commands
+// parsing logs.
+type thresholds struct {
+	strategy   *string
+	relative   *float64
+	percentile *float64
+	overrides  *string
+}
+
+func thresholdFlags(fs *flag.FlagSet) thresholds {
+	return thresholds{
+		fs.String("strategy", "fixed", "Threshold strategy: fixed -t seconds, relative share of the test duration or percentile of the gaps between timestamped lines"),
+		fs.Float64("relative", 0.2, "Share of the test duration for the relative strategy"),
+		fs.Float64("percentile", 99, "Percentile of the gaps between timestamped lines for the percentile strategy"),
+		fs.String("thresholds", "", "JSON file with per test thresholds in seconds overriding the strategy, see thresholds.example.json"),
+	}
+}
+
+// options returns the options with the threshold strategy.
+func (th thresholds) options(opts top.Options) (top.Options, error) {
+	opts.Strategy, opts.Relative, opts.Percentile = top.Strategy(*th.strategy), *th.relative, *th.percentile
+	if *th.overrides == "" {
+		return opts, nil
+	}
+	f, err := os.Open(*th.overrides)
+	if err != nil {
+		return opts, err
+	}
+	defer f.Close()
+	if opts.Overrides, err = top.LoadThresholdOverrides(f); err != nil {
+		return opts, fmt.Errorf("%v: %v", *th.overrides, err)
+	}
+	return opts, nil
+}
+
+func loadPhases(file string) ([]top.PhaseRule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rules, err := top.LoadPhaseRules(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", file, err)
+	}
+	return rules, nil
+}
+
+// load parses the inputs, the JUnit tests are linked to the tests of the
+// console log.
+func (in inputs) load(opts top.Options) (*top.Report, error) {
+	var err error
+	if opts.Start, err = buildStart(*in.file, *in.start); err != nil {
+		return nil, err
+	}
+	if *in.phases != "" {
+		if opts.Phases, err = loadPhases(*in.phases); err != nil {
+			return nil, err
+		}
+	}
+	var report, console *top.Report
+	switch {
+	case *in.ginkgo != "":
+		if *in.file != "" || *in.nodes != "" || *in.junit != "" {
+			return nil, fmt.Errorf("-ginkgo can't be combined with -f, -nodes or -junit")
+		}
+		report, err = parseGinkgo(*in.ginkgo, opts)
+	case *in.file != "" && *in.nodes != "":
+		return nil, fmt.Errorf("-f can't be combined with -nodes")
+	case *in.file == "" && *in.nodes == "" && *in.junit == "":
+		return nil, fmt.Errorf("a log file, node logs, JUnit or ginkgo report is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if *in.nodes != "" {
+		console, err = parseNodes(*in.nodes, opts)
+	} else if *in.file != "" {
+		console, err = parseLog(*in.file, *in.demux, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if *in.junit != "" {
+		if report, err = parseJUnit(*in.junit, opts); err != nil {
+			return nil, err
+		}
+		if console != nil {
+			report.Link(console)
+		}
+	} else if report == nil {
+		report = console
+	}
+	warnJumps(report)
+	return report, nil
+}
@@ -0,0 +1,220 @@
+// Package jenkins fetches build logs of a job from a Jenkins server.
+package jenkins
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Builds selectable by Client.Last.
+const (
+	LastSuccessful = "lastSuccessfulBuild"
+	LastCompleted  = "lastCompletedBuild"
+)
+
+// Client talks to the Jenkins JSON API.
+type Client struct {
+	URL  string
+	HTTP *http.Client
+}
+
+// NewClient returns a client for the Jenkins at base with requests timing
+// out after a minute.
+func NewClient(base string) *Client {
+	return &Client{strings.TrimSuffix(base, "/"), &http.Client{Timeout: time.Minute}}
+}
+
+type buildRef struct {
+	Number int `json:"number"`
+}
+
+// Build is the metadata of a single build.
+type Build struct {
+	Number int    `json:"number"`
+	Result string `json:"result"`
+	// Timestamp is the start of the build in milliseconds since epoch.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Started returns the start of the build.
+func (b Build) Started() time.Time {
+	return time.Unix(0, b.Timestamp*int64(time.Millisecond)).UTC()
+}
+
+type jobInfo struct {
+	Builds              []buildRef `json:"builds"`
+	LastSuccessfulBuild *buildRef  `json:"lastSuccessfulBuild"`
+	LastCompletedBuild  *buildRef  `json:"lastCompletedBuild"`
+}
+
+func (c *Client) jobURL(job string) string {
+	return c.URL + "/job/" + url.PathEscape(job)
+}
+
+func (c *Client) get(u string) (*http.Response, error) {
+	resp, err := c.HTTP.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %v: %v", u, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *Client) getJSON(u string, v interface{}) error {
+	resp, err := c.get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("GET %v: %v", u, err)
+	}
+	return nil
+}
+
+func (c *Client) job(job string) (*jobInfo, error) {
+	info := &jobInfo{}
+	return info, c.getJSON(c.jobURL(job)+"/api/json", info)
+}
+
+// Build returns the metadata of a build.
+func (c *Client) Build(job string, id int) (*Build, error) {
+	b := &Build{}
+	return b, c.getJSON(fmt.Sprintf("%v/%v/api/json", c.jobURL(job), id), b)
+}
+
+// Last returns the number of the LastSuccessful or LastCompleted build.
+func (c *Client) Last(job, which string) (int, error) {
+	info, err := c.job(job)
+	if err != nil {
+		return 0, err
+	}
+	var b *buildRef
+	switch which {
+	case LastSuccessful:
+		b = info.LastSuccessfulBuild
+	case LastCompleted:
+		b = info.LastCompletedBuild
+	default:
+		return 0, fmt.Errorf("unknown build %q", which)
+	}
+	if b == nil {
+		return 0, fmt.Errorf("job %v has no %v", job, which)
+	}
+	return b.Number, nil
+}
+
+// Recent returns the numbers of at most n most recent builds, newest first.
+func (c *Client) Recent(job string, n int) ([]int, error) {
+	info, err := c.job(job)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, n)
+	for i := 0; i < len(info.Builds) && i < n; i++ {
+		ids = append(ids, info.Builds[i].Number)
+	}
+	return ids, nil
+}
+
+// LogFile returns the path of the cached log of a build in dir.
+func LogFile(dir, job string, id int) string {
+	return filepath.Join(dir, fmt.Sprintf("%v-%v.log", id, job))
+}
+
+// BuildFile returns the path of the build metadata cached next to a log.
+func BuildFile(log string) string {
+	return strings.TrimSuffix(log, ".log") + ".json"
+}
+
+// ReadBuild reads the build metadata cached next to a log.
+func ReadBuild(log string) (*Build, error) {
+	data, err := ioutil.ReadFile(BuildFile(log))
+	if err != nil {
+		return nil, err
+	}
+	b := &Build{}
+	return b, json.Unmarshal(data, b)
+}
+
+func (c *Client) saveBuild(log, job string, id int) error {
+	if _, err := os.Stat(BuildFile(log)); err == nil {
+		return nil
+	}
+	b, err := c.Build(job, id)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(BuildFile(log), data, 0666)
+}
+
+// Fetch downloads the console log of a build into dir unless it is already
+// there. It returns the path of the log and whether it was cached. Shell trace
+// lines starting with '+' are dropped. The build metadata is saved next to the
+// log, see ReadBuild.
+func (c *Client) Fetch(dir, job string, id int) (string, bool, error) {
+	path := LogFile(dir, job, id)
+	if _, err := os.Stat(path); err == nil {
+		return path, true, c.saveBuild(path, job, id)
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", false, err
+	}
+	resp, err := c.get(fmt.Sprintf("%v/%v/consoleText", c.jobURL(job), id))
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	tmp, err := ioutil.TempFile(dir, ".fetch-")
+	if err != nil {
+		return "", false, err
+	}
+	defer os.Remove(tmp.Name())
+	if err := stripTrace(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return "", false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", false, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", false, err
+	}
+	return path, false, c.saveBuild(path, job, id)
+}
+
+func stripTrace(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	for {
+		line, err := br.ReadString('\n')
+		if !strings.HasPrefix(line, "+") {
+			if _, werr := bw.WriteString(line); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,110 @@
+package jenkins
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fakeJenkins(consoleHits *int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/job/builds/api/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{
+			"builds": [{"number": 425}, {"number": 424}, {"number": 423}],
+			"lastSuccessfulBuild": {"number": 423},
+			"lastCompletedBuild": {"number": 424}
+		}`)
+	})
+	mux.HandleFunc("/job/builds/423/api/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"number": 423, "result": "SUCCESS", "timestamp": 1522755600000}`)
+	})
+	mux.HandleFunc("/job/builds/423/consoleText", func(w http.ResponseWriter, r *http.Request) {
+		*consoleHits++
+		fmt.Fprint(w, "Started\n+ make test\nApr  3 11:48:35.747: INFO: Running 'oc new-app'\n++ echo\nFinished: SUCCESS")
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestBuilds(t *testing.T) {
+	hits := 0
+	s := fakeJenkins(&hits)
+	defer s.Close()
+	c := NewClient(s.URL + "/")
+
+	for _, tc := range []struct {
+		which  string
+		expect int
+	}{
+		{LastSuccessful, 423},
+		{LastCompleted, 424},
+	} {
+		id, err := c.Last("builds", tc.which)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.which, err)
+		}
+		if id != tc.expect {
+			t.Errorf("%v: Expected %v, got %v", tc.which, tc.expect, id)
+		}
+	}
+	ids, err := c.Recent("builds", 2)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []int{425, 424}) {
+		t.Errorf("Expected [425 424], got %v", ids)
+	}
+	if _, err := c.Last("missing", LastSuccessful); err == nil {
+		t.Errorf("Expected error for missing job")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	hits := 0
+	s := fakeJenkins(&hits)
+	defer s.Close()
+	c := NewClient(s.URL)
+	dir, err := ioutil.TempDir("", "jenkins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path, cached, err := c.Fetch(dir, "builds", 423)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cached || path != LogFile(dir, "builds", 423) {
+		t.Errorf("Expected fresh %v, got %v cached %v", LogFile(dir, "builds", 423), path, cached)
+	}
+	content, _ := ioutil.ReadFile(path)
+	expect := "Started\nApr  3 11:48:35.747: INFO: Running 'oc new-app'\nFinished: SUCCESS"
+	if string(content) != expect {
+		t.Errorf("Expected %q, got %q", expect, content)
+	}
+
+	b, err := ReadBuild(path)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if b.Result != "SUCCESS" || !b.Started().Equal(time.Date(2018, 4, 3, 11, 40, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected build %+v started %v", b, b.Started())
+	}
+
+	if _, cached, err = c.Fetch(dir, "builds", 423); err != nil || !cached {
+		t.Errorf("Expected cached log, got cached %v err %v", cached, err)
+	}
+	if hits != 1 {
+		t.Errorf("Expected 1 download, got %v", hits)
+	}
+	if _, _, err := c.Fetch(dir, "builds", 1); err == nil {
+		t.Errorf("Expected error for missing build")
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("Expected only the cached log and build in %v, got %v files", dir, len(files))
+	}
+}
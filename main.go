@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"analyze", "identify slow windows in a log and write them to an output folder", analyze},
+	{"graph", "generate html graph from analyze output", graph},
+	{"fetch", "download build logs from Jenkins into a local cache", fetch},
+	{"compare", "report tests that got slower between two builds", compareBuilds},
+	{"history", "store analyzed builds and query test duration trends", historyCmd},
+	{"serve", "browse the analyze output and the history over http", serve},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %v <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10v %v\n", c.name, c.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%v <command> -h' for the command flags.\n", os.Args[0])
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%v: %v\n", c.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
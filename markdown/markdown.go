@@ -0,0 +1,137 @@
+// Package markdown writes the slowest tests as a Markdown report, e.g. for a
+// pull request comment.
+package markdown
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+// maxWindows is the number of the slowest windows shown per test.
+const maxWindows = 3
+
+// Origin is the source of the origin extended tests.
+const Origin = "https://github.com/openshift/origin/blob/master"
+
+var locationRegexp = regexp.MustCompile(`^(/test/extended/.*\.go):([0-9]+)$`)
+var cellEscaper = strings.NewReplacer(`|`, `\|`, "\n", " ", "\r", "")
+
+func duration(s float64) time.Duration {
+	return top.Seconds(s).Round(time.Millisecond)
+}
+
+// link returns a link to the test source when the name is file:line and
+// source is set, otherwise the escaped name.
+func link(name, source string) string {
+	cell := "`" + cellEscaper.Replace(strings.Replace(name, "`", "'", -1)) + "`"
+	m := locationRegexp.FindStringSubmatch(name)
+	if source == "" || len(m) < 3 {
+		return cell
+	}
+	return fmt.Sprintf("[%v](%v%v#L%v)", cell, strings.TrimSuffix(source, "/"), m[1], m[2])
+}
+
+// fence returns a code fence longer than any backtick run in the lines.
+func fence(lines []string) string {
+	longest := 0
+	for _, l := range lines {
+		run := 0
+		for _, c := range l {
+			if c != '`' {
+				run = 0
+				continue
+			}
+			if run++; run > longest {
+				longest = run
+			}
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+func writeCode(w io.Writer, lines []string) {
+	f := fence(lines)
+	fmt.Fprintf(w, "%v\n%v\n%v\n", f, strings.Join(lines, "\n"), f)
+}
+
+func writeSummary(w io.Writer, s top.Summary) {
+	statuses := make([]string, 0, len(s.Status))
+	for st := range s.Status {
+		statuses = append(statuses, fmt.Sprintf("%v %v", s.Status[st], st))
+	}
+	sort.Strings(statuses)
+	fmt.Fprintf(w, "**%v tests** taking **%v**", s.Tests, duration(s.Time))
+	if len(statuses) > 0 {
+		fmt.Fprintf(w, " (%v)", strings.Join(statuses, ", "))
+	}
+	fmt.Fprintf(w, "\n\n")
+	fmt.Fprintf(w, "| | Tests | Time | Share |\n|---|---:|---:|---:|\n")
+	fmt.Fprintf(w, "| slow | %v | %v | %.1f%% |\n", s.Slow.Tests, duration(s.Slow.Time), s.Slow.Percent)
+	fmt.Fprintf(w, "| fast | %v | %v | %.1f%% |\n", s.Fast.Tests, duration(s.Fast.Time), s.Fast.Percent)
+}
+
+func writeDetails(w io.Writer, i int, t top.Test) {
+	if len(t.Windows) == 0 && len(t.Phases.Phases) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n<details>\n<summary>%v. %v - %v</summary>\n\n", i, strings.Replace(cellEscaper.Replace(t.Name()), "<", "&lt;", -1), duration(t.Time))
+	if len(t.Phases.Phases) > 0 {
+		fmt.Fprintf(w, "| Phase | Time |\n|---|---:|\n")
+		for _, p := range t.Phases.Phases {
+			d := "unfinished"
+			if !p.End.IsZero() {
+				d = p.Duration().String()
+			}
+			fmt.Fprintf(w, "| %v%v | %v |\n", strings.Repeat("&nbsp;&nbsp;", p.Depth), cellEscaper.Replace(p.Name), d)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	for j, win := range t.Windows {
+		if j == maxWindows {
+			fmt.Fprintf(w, "%v more windows\n\n", len(t.Windows)-maxWindows)
+			break
+		}
+		lines := make([]string, 0, len(win.TimedWindow))
+		for _, l := range win.TimedWindow {
+			lines = append(lines, l.Line)
+		}
+		label := "Window"
+		if win.Gap {
+			label = "Gap"
+		}
+		fmt.Fprintf(w, "%v %v - %v\n\n", label, j, win.Time().Round(time.Millisecond))
+		writeCode(w, lines)
+		fmt.Fprintf(w, "\n")
+	}
+	fmt.Fprintf(w, "</details>\n")
+}
+
+// Write writes the suite totals, a table of the tests, already sorted from
+// the slowest, and their slowest windows and phases in collapsible sections.
+// The test names in file:line form link to the source when it is not empty,
+// e.g. Origin.
+func Write(w io.Writer, s top.Summary, tests []top.Test, source string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "## Test durations\n\n")
+	writeSummary(bw, s)
+	if len(tests) > 0 {
+		fmt.Fprintf(bw, "\n### Slowest tests\n\n| # | Time | Status | Test |\n|---:|---:|---|---|\n")
+		for i, t := range tests {
+			fmt.Fprintf(bw, "| %v | %v | %v | %v |\n", i+1, duration(t.Time), t.Status, link(t.Name(), source))
+		}
+		for i, t := range tests {
+			writeDetails(bw, i+1, t)
+		}
+	}
+	return bw.Flush()
+}
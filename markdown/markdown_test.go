@@ -0,0 +1,61 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+func TestWrite(t *testing.T) {
+	start := time.Date(2018, 4, 3, 11, 48, 0, 0, time.UTC)
+	tests := []top.Test{
+		{
+			Status: top.Passed,
+			Time:   552,
+			Lines:  []string{"/go/src/github.com/openshift/origin/test/extended/builds/pipeline.go:437"},
+			Phases: top.Phases{Phases: []top.Phase{{Name: "docker build", Start: start, End: start.Add(30500 * time.Millisecond)}, {Name: "docker push", Start: start, Depth: 1}}},
+			Windows: []top.Window{{TimedWindow: []top.Line{
+				{Time: start, Line: "Apr  3 11:48:00.000: INFO: Running 'oc start-build'"},
+				{Time: start.Add(9 * time.Minute), Line: "Apr  3 11:57:00.000: INFO: ```done```"},
+			}}},
+		},
+		{Spec: "[builds] a | b", Status: top.Failed, Time: 6.5},
+	}
+	s := top.Summary{Tests: 2, Time: 558.5, Status: map[string]int{top.Passed: 1, top.Failed: 1}, Slow: top.Share{Tests: 1, Time: 552, Percent: 98.8}, Fast: top.Share{Tests: 1, Time: 6.5, Percent: 1.2}}
+	buf := &bytes.Buffer{}
+	if err := Write(buf, s, tests, Origin+"/"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "## Test durations\n\n" +
+		"**2 tests** taking **9m18.5s** (1 failed, 1 passed)\n\n" +
+		"| | Tests | Time | Share |\n|---|---:|---:|---:|\n" +
+		"| slow | 1 | 9m12s | 98.8% |\n" +
+		"| fast | 1 | 6.5s | 1.2% |\n\n" +
+		"### Slowest tests\n\n| # | Time | Status | Test |\n|---:|---:|---|---|\n" +
+		"| 1 | 9m12s | passed | [`/test/extended/builds/pipeline.go:437`](https://github.com/openshift/origin/blob/master/test/extended/builds/pipeline.go#L437) |\n" +
+		"| 2 | 6.5s | failed | `[builds] a \\| b` |\n\n" +
+		"<details>\n<summary>1. /test/extended/builds/pipeline.go:437 - 9m12s</summary>\n\n" +
+		"| Phase | Time |\n|---|---:|\n" +
+		"| docker build | 30.5s |\n" +
+		"| &nbsp;&nbsp;docker push | unfinished |\n\n" +
+		"Window 0 - 9m0s\n\n" +
+		"````\nApr  3 11:48:00.000: INFO: Running 'oc start-build'\nApr  3 11:57:00.000: INFO: ```done```\n````\n\n" +
+		"</details>\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %v, got %v", expected, buf.String())
+	}
+	again := &bytes.Buffer{}
+	Write(again, s, tests, Origin)
+	if again.String() != buf.String() {
+		t.Errorf("Expected the same report when written again")
+	}
+}
+
+func TestLinkWithoutSource(t *testing.T) {
+	if l := link("/test/extended/builds/pipeline.go:437", ""); strings.Contains(l, "](") {
+		t.Errorf("Expected no link without source, got %v", l)
+	}
+}
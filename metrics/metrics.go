@@ -0,0 +1,162 @@
+// Package metrics writes the test durations in the OpenMetrics text format and
+// pushes them to a Prometheus Pushgateway.
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+// ContentType of the OpenMetrics text format.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// pushContentType is the Prometheus text format the Pushgateway parses, the
+// OpenMetrics UNIT and EOF lines are comments in it.
+const pushContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type family struct {
+	name string
+	help string
+	unit string
+}
+
+var (
+	testDuration  = family{"test_eval_test_duration_seconds", "Duration of the test.", "seconds"}
+	slowWindows   = family{"test_eval_test_slow_windows", "Number of slow windows in the test output.", ""}
+	phaseDuration = family{"test_eval_phase_duration_seconds", "Total duration of the phases of the test, e.g. docker build.", "seconds"}
+)
+
+type sample struct {
+	labels [][2]string
+	value  float64
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (s sample) write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%v{", name)
+	for i, l := range s.labels {
+		if i > 0 {
+			fmt.Fprint(w, ",")
+		}
+		fmt.Fprintf(w, `%v="%v"`, l[0], labelEscaper.Replace(l[1]))
+	}
+	fmt.Fprintf(w, "} %v\n", strconv.FormatFloat(s.value, 'g', -1, 64))
+}
+
+// names returns the test names, repeated names get a " #n" suffix so every
+// test is a separate series.
+func names(tests []top.Test) []string {
+	n := make([]string, len(tests))
+	seen := make(map[string]int)
+	for i, t := range tests {
+		n[i] = t.Name()
+		if seen[n[i]]++; seen[n[i]] > 1 {
+			n[i] = fmt.Sprintf("%v #%v", n[i], seen[n[i]])
+		}
+	}
+	return n
+}
+
+// Write writes the duration, the slow window count and the phase durations of
+// every test as OpenMetrics gauges labelled with the test name, the file:line
+// of specs found in the log and the job when not empty.
+func Write(w io.Writer, r *top.Report, job string) error {
+	bw := bufio.NewWriter(w)
+	samples := make(map[family][]sample)
+	for i, name := range names(r.Tests) {
+		t := r.Tests[i]
+		labels := [][2]string{{"test", name}}
+		if l := t.Location(); t.Spec != "" && l != "" {
+			labels = append(labels, [2]string{"location", l})
+		}
+		if job != "" {
+			labels = append(labels, [2]string{"job", job})
+		}
+		samples[testDuration] = append(samples[testDuration], sample{labels, t.Time})
+		samples[slowWindows] = append(samples[slowWindows], sample{labels, float64(len(t.Windows))})
+		phases := make(map[string]float64)
+		for _, p := range t.Phases.Phases {
+			phases[p.Name] += p.Duration().Seconds()
+		}
+		phaseNames := make([]string, 0, len(phases))
+		for p := range phases {
+			phaseNames = append(phaseNames, p)
+		}
+		sort.Strings(phaseNames)
+		for _, p := range phaseNames {
+			pl := append(append([][2]string{}, labels...), [2]string{"phase", p})
+			samples[phaseDuration] = append(samples[phaseDuration], sample{pl, phases[p]})
+		}
+	}
+	for _, f := range []family{testDuration, slowWindows, phaseDuration} {
+		fmt.Fprintf(bw, "# TYPE %v gauge\n", f.name)
+		if f.unit != "" {
+			fmt.Fprintf(bw, "# UNIT %v %v\n", f.name, f.unit)
+		}
+		fmt.Fprintf(bw, "# HELP %v %v\n", f.name, f.help)
+		for _, s := range samples[f] {
+			s.write(bw, f.name)
+		}
+	}
+	fmt.Fprintf(bw, "# EOF\n")
+	return bw.Flush()
+}
+
+// groupURL returns the Pushgateway URL of the job group, job names with a
+// slash are base64 encoded.
+func groupURL(gateway, job string) (string, error) {
+	u, err := url.Parse(strings.TrimSuffix(gateway, "/"))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid Pushgateway URL %q", gateway)
+	}
+	if strings.Contains(job, "/") {
+		return u.String() + "/metrics/job@base64/" + base64.RawURLEncoding.EncodeToString([]byte(job)), nil
+	}
+	return u.String() + "/metrics/job/" + url.PathEscape(job), nil
+}
+
+// Push replaces the metrics of the job on a Pushgateway, e.g.
+// http://localhost:9091. The job label comes from the group.
+func Push(client *http.Client, gateway, job string, r *top.Report) error {
+	if job == "" {
+		return fmt.Errorf("job is required to push metrics")
+	}
+	u, err := groupURL(gateway, job)
+	if err != nil {
+		return err
+	}
+	body := &bytes.Buffer{}
+	if err := Write(body, r, ""); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", pushContentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("PUT %v: %v %s", u, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
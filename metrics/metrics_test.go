@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+func report() *top.Report {
+	start := time.Date(2018, 4, 3, 11, 49, 0, 0, time.UTC)
+	build := top.Phase{Name: "docker build", Start: start, End: start.Add(30 * time.Second)}
+	push := top.Phase{Name: "docker push", Start: start, End: start.Add(5 * time.Second)}
+	return &top.Report{Tests: []top.Test{
+		{Time: 552, Lines: []string{"/go/src/github.com/openshift/origin/test/extended/builds/pipeline.go:437"}, Windows: make([]top.Window, 2), Phases: top.Phases{Phases: []top.Phase{build, push, build}}},
+		{Spec: `[builds] "quoted" spec`, Time: 6.5, Lines: []string{"/go/src/github.com/openshift/origin/test/extended/builds/digest.go:65"}},
+		{Spec: `[builds] "quoted" spec`, Time: 1},
+	}}
+}
+
+func TestWrite(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Write(buf, report(), "extended_builds"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `# TYPE test_eval_test_duration_seconds gauge
+# UNIT test_eval_test_duration_seconds seconds
+# HELP test_eval_test_duration_seconds Duration of the test.
+test_eval_test_duration_seconds{test="/test/extended/builds/pipeline.go:437",job="extended_builds"} 552
+test_eval_test_duration_seconds{test="[builds] \"quoted\" spec",location="/test/extended/builds/digest.go:65",job="extended_builds"} 6.5
+test_eval_test_duration_seconds{test="[builds] \"quoted\" spec #2",job="extended_builds"} 1
+# TYPE test_eval_test_slow_windows gauge
+# HELP test_eval_test_slow_windows Number of slow windows in the test output.
+test_eval_test_slow_windows{test="/test/extended/builds/pipeline.go:437",job="extended_builds"} 2
+test_eval_test_slow_windows{test="[builds] \"quoted\" spec",location="/test/extended/builds/digest.go:65",job="extended_builds"} 0
+test_eval_test_slow_windows{test="[builds] \"quoted\" spec #2",job="extended_builds"} 0
+# TYPE test_eval_phase_duration_seconds gauge
+# UNIT test_eval_phase_duration_seconds seconds
+# HELP test_eval_phase_duration_seconds Total duration of the phases of the test, e.g. docker build.
+test_eval_phase_duration_seconds{test="/test/extended/builds/pipeline.go:437",job="extended_builds",phase="docker build"} 60
+test_eval_phase_duration_seconds{test="/test/extended/builds/pipeline.go:437",job="extended_builds",phase="docker push"} 5
+# EOF
+`
+	if buf.String() != expected {
+		t.Errorf("Expected %v, got %v", expected, buf.String())
+	}
+}
+
+func TestPush(t *testing.T) {
+	pushed := make(map[string]string)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || !strings.HasPrefix(r.Header.Get("Content-Type"), "text/plain") {
+			http.Error(w, "unexpected request", http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		pushed[r.URL.EscapedPath()] = string(body)
+	}))
+	defer gateway.Close()
+	if err := Push(gateway.Client(), gateway.URL+"/", "extended_builds", report()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, ok := pushed["/metrics/job/extended_builds"]
+	if !ok || strings.Contains(body, `job="`) || !strings.Contains(body, `test_eval_test_duration_seconds{test="/test/extended/builds/pipeline.go:437"} 552`) {
+		t.Errorf("Expected the metrics without job label in the job group, got %v", pushed)
+	}
+	if err := Push(gateway.Client(), gateway.URL, "folder/job", report()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := pushed["/metrics/job@base64/Zm9sZGVyL2pvYg"]; !ok {
+		t.Errorf("Expected base64 encoded job group, got %v", pushed)
+	}
+	if err := Push(gateway.Client(), gateway.URL, "", report()); err == nil {
+		t.Errorf("Expected error without job")
+	}
+}
@@ -0,0 +1,250 @@
+// Package otlp exports the parsed tests as OpenTelemetry traces in the
+// OTLP/JSON encoding, written to a file or sent to a collector over HTTP.
+package otlp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+// Span kinds and status codes of the OTLP protocol.
+const (
+	KindInternal = 1
+	StatusOK     = 1
+	StatusError  = 2
+)
+
+// Build identifies the analyzed build in the span attributes.
+type Build struct {
+	Job string
+	ID  int
+}
+
+// Value is an attribute value, only one of the fields is set.
+type Value struct {
+	String *string `json:"stringValue,omitempty"`
+	Int    *string `json:"intValue,omitempty"`
+	Bool   *bool   `json:"boolValue,omitempty"`
+}
+
+// Attribute is a key value pair.
+type Attribute struct {
+	Key   string `json:"key"`
+	Value Value  `json:"value"`
+}
+
+func str(key, v string) Attribute {
+	return Attribute{key, Value{String: &v}}
+}
+
+func integer(key string, v int) Attribute {
+	s := strconv.Itoa(v)
+	return Attribute{key, Value{Int: &s}}
+}
+
+func boolean(key string, v bool) Attribute {
+	return Attribute{key, Value{Bool: &v}}
+}
+
+// Status of a span.
+type Status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Span is a single operation, the times are nanoseconds since epoch encoded
+// as strings.
+type Span struct {
+	TraceID      string      `json:"traceId"`
+	SpanID       string      `json:"spanId"`
+	ParentSpanID string      `json:"parentSpanId,omitempty"`
+	Name         string      `json:"name"`
+	Kind         int         `json:"kind"`
+	Start        string      `json:"startTimeUnixNano"`
+	End          string      `json:"endTimeUnixNano"`
+	Attributes   []Attribute `json:"attributes,omitempty"`
+	Status       Status      `json:"status"`
+}
+
+// Scope is the instrumentation scope.
+type Scope struct {
+	Name string `json:"name"`
+}
+
+// ScopeSpans are the spans of a scope.
+type ScopeSpans struct {
+	Scope Scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+// Resource describes the entity producing the spans.
+type Resource struct {
+	Attributes []Attribute `json:"attributes"`
+}
+
+// ResourceSpans are the spans of a resource.
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+// Traces is the ExportTraceServiceRequest message.
+type Traces struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+func nanos(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// ids derives the trace and span IDs from the build and the suite start, so
+// exporting a build again produces the same spans.
+type ids struct {
+	seed []byte
+	n    uint64
+}
+
+func (id *ids) next(size int) string {
+	h := sha256.New()
+	h.Write(id.seed)
+	binary.Write(h, binary.BigEndian, id.n)
+	id.n++
+	return hex.EncodeToString(h.Sum(nil)[:size])
+}
+
+// epochYear is the year of the logs without a year, a leap year like year 0
+// to keep Feb 29.
+const epochYear = 2000
+
+// New converts the tests into a trace with the suite as the root span, a child
+// span per test and grandchild spans for the phases and the slow windows.
+func New(r *top.Report, b Build) Traces {
+	starts, origin := r.Starts()
+	// the logs without full timestamps start in year 0, out of the UnixNano
+	// range, their times are moved to the epoch year so the IDs and times do
+	// not depend on the time of the export
+	years := 0
+	if origin.Year() <= 1 {
+		years = epochYear - origin.Year()
+	}
+	at := func(t time.Time) time.Time { return t.AddDate(years, 0, 0) }
+	gen := &ids{seed: []byte(fmt.Sprintf("%v/%v/%v", b.Job, b.ID, at(origin).UnixNano()))}
+	traceID := gen.next(16)
+	span := func(parent, name string, start, end time.Time, attrs ...Attribute) Span {
+		return Span{traceID, gen.next(8), parent, name, KindInternal, nanos(at(start)), nanos(at(end)), attrs, Status{}}
+	}
+	build := make([]Attribute, 0, 2)
+	if b.Job != "" {
+		build = append(build, str("ci.job", b.Job))
+	}
+	if b.ID != 0 {
+		build = append(build, integer("ci.build", b.ID))
+	}
+
+	suite := span("", "suite", origin, origin, build...)
+	suite.Status.Code = StatusOK
+	suiteEnd := origin
+	spans := []Span{suite}
+	for i, t := range r.Tests {
+		start, end := starts[i], starts[i].Add(top.Seconds(t.Time))
+		if end.After(suiteEnd) {
+			suiteEnd = end
+		}
+		attrs := append([]Attribute{str("test.name", t.Name()), str("test.status", t.Status), boolean("test.slow", t.Slow)}, build...)
+		if l := t.Location(); l != "" {
+			attrs = append(attrs, str("test.location", l))
+		}
+		if t.Node != 0 {
+			attrs = append(attrs, integer("ginkgo.node", t.Node))
+		}
+		ts := span(suite.SpanID, t.Name(), start, end, attrs...)
+		ts.Status.Code = StatusOK
+		if t.Status == top.Failed {
+			ts.Status = Status{StatusError, "test failed"}
+			suite.Status = Status{StatusError, "tests failed"}
+		}
+		spans = append(spans, ts)
+		for _, p := range t.Phases.Phases {
+			s := span(ts.SpanID, p.Name, p.Start, p.Start.Add(p.Duration()), str("phase.start", p.StartLine))
+			if p.End.IsZero() {
+				s.Status = Status{StatusError, "unfinished"}
+			} else {
+				s.Attributes = append(s.Attributes, str("phase.end", p.EndLine))
+			}
+			spans = append(spans, s)
+		}
+		for _, w := range t.Windows {
+			if len(w.TimedWindow) == 0 {
+				continue
+			}
+			first, last := w.TimedWindow[0], w.TimedWindow[len(w.TimedWindow)-1]
+			name := "slow window"
+			if w.Gap {
+				name = "slow gap"
+			}
+			spans = append(spans, span(ts.SpanID, name, first.Time, last.Time, str("window.first", first.Line), str("window.last", last.Line)))
+		}
+	}
+	suite.End = nanos(at(suiteEnd))
+	spans[0] = suite
+	return Traces{[]ResourceSpans{{
+		Resource{append([]Attribute{str("service.name", "test_eval")}, build...)},
+		[]ScopeSpans{{Scope{"test_eval"}, spans}},
+	}}}
+}
+
+// Write writes the traces as OTLP/JSON.
+func Write(w io.Writer, t Traces) error {
+	return json.NewEncoder(w).Encode(t)
+}
+
+// tracesURL appends the traces path to an endpoint without a path, as the
+// OTEL_EXPORTER_OTLP_ENDPOINT does.
+func tracesURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid OTLP endpoint %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
+
+// Send posts the traces to an OTLP/HTTP collector, e.g.
+// http://localhost:4318.
+func Send(client *http.Client, endpoint string, t Traces) error {
+	u, err := tracesURL(endpoint)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(u, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("POST %v: %v %s", u, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package otlp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+const log = `------------------------------
+[builds] pipeline
+/go/src/github.com/openshift/origin/test/extended/builds/pipeline.go:437
+Apr  3 11:48:35.000: INFO: Running 'oc start-build sample'
+2018-04-03T11:49:00Z Step 1/3 : FROM centos
+2018-04-03T11:49:30Z Successfully built 0123456789ab
+Apr  3 11:57:40.000: INFO: Done waiting for sample-1
+Apr  3 11:57:41.000: INFO: Running 'oc delete bc sample'
+
+• Failure [546.000 seconds]
+`
+
+func parse(t *testing.T) *top.Report {
+	r, err := top.Parse(strings.NewReader(log), top.Options{WindowSize: 2, Threshold: 120e9})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return r
+}
+
+func attr(s Span, key string) string {
+	for _, a := range s.Attributes {
+		if a.Key != key {
+			continue
+		}
+		switch {
+		case a.Value.String != nil:
+			return *a.Value.String
+		case a.Value.Int != nil:
+			return *a.Value.Int
+		}
+	}
+	return ""
+}
+
+func TestNew(t *testing.T) {
+	tr := New(parse(t), Build{"extended_builds", 423})
+	spans := tr.ResourceSpans[0].ScopeSpans[0].Spans
+	expects := []struct {
+		name   string
+		parent int
+		start  string
+		end    string
+	}{
+		{"suite", -1, "1522756115000000000", "1522756661000000000"},
+		{"/test/extended/builds/pipeline.go:437", 0, "1522756115000000000", "1522756661000000000"},
+		{"docker build", 1, "1522756140000000000", "1522756170000000000"},
+		{"slow window", 1, "1522756115000000000", "1522756660000000000"},
+	}
+	if len(spans) != len(expects) {
+		t.Fatalf("Expected %v spans, got %+v", len(expects), spans)
+	}
+	for i, e := range expects {
+		s := spans[i]
+		parent := ""
+		if e.parent >= 0 {
+			parent = spans[e.parent].SpanID
+		}
+		if s.Name != e.name || s.ParentSpanID != parent || s.Start != e.start || s.End != e.end || s.TraceID != spans[0].TraceID {
+			t.Errorf("Expected %v from %v to %v, got %+v", e.name, e.start, e.end, s)
+		}
+	}
+	if attr(spans[1], "test.location") != "/test/extended/builds/pipeline.go:437" || attr(spans[1], "ci.job") != "extended_builds" || attr(spans[1], "ci.build") != "423" {
+		t.Errorf("Unexpected test attributes %+v", spans[1].Attributes)
+	}
+	if spans[0].Status.Code != StatusError || spans[1].Status.Code != StatusError {
+		t.Errorf("Expected failed suite and test, got %v and %v", spans[0].Status, spans[1].Status)
+	}
+	if again := New(parse(t), Build{"extended_builds", 423}); again.ResourceSpans[0].ScopeSpans[0].Spans[1].SpanID != spans[1].SpanID {
+		t.Errorf("Expected the same span IDs for the same build")
+	}
+}
+
+func TestNewWithoutYear(t *testing.T) {
+	log := `------------------------------
+Dec 31 23:59:00.000: INFO: Running 'oc start-build sample'
+Jan  1 00:03:00.000: INFO: Running 'oc delete bc sample'
+
+• [SLOW TEST:180.000 seconds]
+`
+	r, err := top.Parse(strings.NewReader(log), top.Options{WindowSize: 2, Threshold: 120e9})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	spans := New(r, Build{}).ResourceSpans[0].ScopeSpans[0].Spans
+	start := time.Date(epochYear, 12, 31, 23, 59, 0, 0, time.UTC)
+	if len(spans) != 3 || spans[0].Start != nanos(start) || spans[0].End != nanos(start.Add(3*time.Minute)) || spans[2].End != nanos(start.Add(4*time.Minute)) {
+		t.Errorf("Expected the spans from %v, got %+v", nanos(start), spans)
+	}
+}
+
+func TestSend(t *testing.T) {
+	received := Traces{}
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer collector.Close()
+	tr := New(parse(t), Build{"extended_builds", 423})
+	if err := Send(collector.Client(), collector.URL, tr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(received.ResourceSpans) != 1 || len(received.ResourceSpans[0].ScopeSpans[0].Spans) != 4 {
+		t.Errorf("Expected 4 spans at the collector, got %+v", received)
+	}
+	if err := Send(collector.Client(), collector.URL+"/v1/logs", tr); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected the collector error, got %v", err)
+	}
+	if err := Send(collector.Client(), "localhost:4318", tr); err == nil {
+		t.Errorf("Expected invalid endpoint error")
+	}
+}
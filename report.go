@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+// reportTest is a test of the interactive report with the output written by
+// analyze.
+type reportTest struct {
+	top.Blocks
+	Output string `json:"output"`
+}
+
+// testOutput reads the entire output of the i-th test, 1 based, from its file
+// written by analyze next to stats.json, empty when there is none.
+func testOutput(dir string, i int) string {
+	files, _ := filepath.Glob(filepath.Join(dir, fmt.Sprintf("%04d_*", i)))
+	if len(files) == 0 {
+		return ""
+	}
+	data, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		return ""
+	}
+	out := string(data)
+	if i := strings.Index(out, "\nEntire output:\n"); i >= 0 {
+		return out[i+len("\nEntire output:\n"):]
+	}
+	return out
+}
+
+func reportTests(dir string, stats []top.Blocks) []reportTest {
+	tests := make([]reportTest, len(stats))
+	for i, s := range stats {
+		tests[i] = reportTest{s, testOutput(dir, i+1)}
+	}
+	return tests
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(reportPage))
+
+func renderReport(out io.Writer, tests []reportTest) error {
+	w := bufio.NewWriter(out)
+	if err := reportTemplate.Execute(w, struct {
+		Chart template.JS
+		Tests []reportTest
+	}{template.JS(chartJS), tests}); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+const reportPage = `<!DOCTYPE HTML>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Test durations</title>
+<style>
+	body { font-family: sans-serif; margin: 1em; }
+	#filters { margin-bottom: 1em; }
+	#filters label { margin-right: 1em; }
+	table { border-collapse: collapse; width: 100%; }
+	th { cursor: pointer; text-align: left; background: #eee; }
+	th, td { padding: 0.2em 0.5em; border-bottom: 1px solid #ddd; }
+	td.time { text-align: right; }
+	tr.selected { background: #ffd; }
+	tr.failed td.status { color: #c00; }
+	#details { white-space: pre-wrap; font-family: monospace; font-size: 0.85em; background: #f6f6f6; padding: 0.5em; }
+	#details h3 { font-family: sans-serif; }
+</style>
+</head>
+<body>
+<div id="filters">
+	<label>Search <input id="search" type="text" size="40"></label>
+	<label><input id="fast" type="checkbox" checked> fast blocks</label>
+	<label><input id="slow" type="checkbox" checked> slow blocks</label>
+	<label>Min block duration <input id="min" type="number" min="0" value="0" size="6">s</label>
+</div>
+<canvas id="chart" height="100"></canvas>
+<table>
+	<thead><tr>
+		<th data-key="index">#</th>
+		<th data-key="name">Test</th>
+		<th data-key="status">Status</th>
+		<th data-key="time">Time (s)</th>
+		<th data-key="blocks">Blocks</th>
+		<th data-key="slowest">Slowest block (s)</th>
+	</tr></thead>
+	<tbody id="tests"></tbody>
+</table>
+<div id="details"></div>
+<script>
+{{.Chart}}
+</script>
+<script>
+var tests = {{.Tests}};
+var colors = { fast: "rgba(128,200,128,0.7)", slow: "rgba(200,128,128,0.7)" };
+var sortKey = "index", sortDesc = false, chart = null, shown = [];
+
+tests.forEach(function (t, i) {
+	t.index = i + 1;
+	t.block = t.block || [];
+	t.slowest = 0;
+	t.block.forEach(function (b) {
+		t.slowest = Math.max(t.slowest, b.end - b.start);
+	});
+	if (!t.time && t.block.length) {
+		t.time = t.block[t.block.length - 1].end;
+	}
+});
+
+function blockShown(b) {
+	return document.getElementById(b.blockType).checked &&
+		b.end - b.start >= (parseFloat(document.getElementById("min").value) || 0);
+}
+
+function filtered() {
+	var search = document.getElementById("search").value.toLowerCase();
+	return tests.filter(function (t) {
+		if (search && t.name.toLowerCase().indexOf(search) < 0) {
+			return false;
+		}
+		return t.block.length === 0 || t.block.some(blockShown);
+	}).sort(function (a, b) {
+		var x = sortKey === "blocks" ? a.block.length : a[sortKey];
+		var y = sortKey === "blocks" ? b.block.length : b[sortKey];
+		var c = x < y ? -1 : x > y ? 1 : 0;
+		return sortDesc ? -c : c;
+	});
+}
+
+function text(s) {
+	return document.createTextNode(s === undefined ? "" : String(s));
+}
+
+function cell(row, value, cls) {
+	var td = document.createElement("td");
+	if (cls) {
+		td.className = cls;
+	}
+	td.appendChild(text(value));
+	row.appendChild(td);
+}
+
+function round(s) {
+	return Math.round(s * 1000) / 1000;
+}
+
+function show(t, b) {
+	var d = document.getElementById("details");
+	d.innerHTML = "";
+	var h = document.createElement("h3");
+	h.appendChild(text(t.index + ". " + t.name + " - " + round(t.time) + "s"));
+	d.appendChild(h);
+	if (b) {
+		var bh = document.createElement("h3");
+		bh.appendChild(text(b.blockType + " block " + round(b.start) + "s - " + round(b.end) + "s"));
+		d.appendChild(bh);
+		d.appendChild(text(b.lines.join("\n")));
+	}
+	var oh = document.createElement("h3");
+	oh.appendChild(text("Entire output"));
+	d.appendChild(oh);
+	d.appendChild(text(t.output || "not found next to stats.json"));
+	var rows = document.getElementById("tests").children;
+	for (var i = 0; i < rows.length; i++) {
+		rows[i].className = rows[i].test === t ? "selected" : rows[i].test.status;
+	}
+	d.scrollIntoView();
+}
+
+function renderTable() {
+	var body = document.getElementById("tests");
+	body.innerHTML = "";
+	shown.forEach(function (t) {
+		var row = document.createElement("tr");
+		row.test = t;
+		row.className = t.status || "";
+		cell(row, t.index);
+		cell(row, t.name);
+		cell(row, t.status || "", "status");
+		cell(row, round(t.time), "time");
+		cell(row, t.block.length, "time");
+		cell(row, round(t.slowest), "time");
+		row.onclick = function () { show(t); };
+		body.appendChild(row);
+	});
+}
+
+function renderChart() {
+	var max = 0;
+	shown.forEach(function (t) { max = Math.max(max, t.block.length); });
+	var datasets = [];
+	for (var i = 0; i < max; i++) {
+		var ds = { label: "block " + i, data: [], backgroundColor: [], blocks: [] };
+		shown.forEach(function (t) {
+			var b = t.block[i];
+			var visible = b && blockShown(b);
+			ds.data.push(visible ? round(b.end - b.start) : 0);
+			ds.backgroundColor.push(b ? colors[b.blockType] : colors.fast);
+			ds.blocks.push(visible ? b : null);
+		});
+		datasets.push(ds);
+	}
+	if (chart) {
+		chart.destroy();
+	}
+	chart = new Chart(document.getElementById("chart").getContext("2d"), {
+		type: "bar",
+		data: { labels: shown.map(function (t) { return (t.index + ". " + t.name).slice(0, 40); }), datasets: datasets },
+		options: {
+			legend: { display: false },
+			tooltips: { enabled: false },
+			scales: {
+				xAxes: [{ stacked: true }],
+				yAxes: [{ stacked: true, ticks: { beginAtZero: true } }]
+			},
+			onClick: function (evt) {
+				var el = chart.getElementAtEvent(evt)[0];
+				if (!el) {
+					return;
+				}
+				var t = shown[el._index];
+				show(t, datasets[el._datasetIndex].blocks[el._index]);
+			}
+		}
+	});
+}
+
+function render() {
+	shown = filtered();
+	renderTable();
+	renderChart();
+}
+
+Array.prototype.forEach.call(document.querySelectorAll("th"), function (th) {
+	th.onclick = function () {
+		var key = th.getAttribute("data-key");
+		sortDesc = key === sortKey ? !sortDesc : key !== "index" && key !== "name" && key !== "status";
+		sortKey = key;
+		render();
+	};
+});
+["search", "fast", "slow", "min"].forEach(function (id) {
+	document.getElementById(id).oninput = render;
+	document.getElementById(id).onchange = render;
+});
+render();
+</script>
+</body>
+</html>
+`
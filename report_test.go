@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+func TestRenderReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	result := "time: 7s\n\nWindow 0 - 7s\nline\n\n\nEntire output:\nfirst line\n</script><b>second</b>\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "0001_7_test.go:1"), []byte(result), 0666); err != nil {
+		t.Fatal(err)
+	}
+	stats := []top.Blocks{
+		{Name: "test.go:1", Time: 7, Blocks: []top.Block{{Lines: []string{"line"}, Start: 0, End: 7, BlockType: "slow"}}},
+		{Name: "other.go:2", Time: 1},
+	}
+	tests := reportTests(dir, stats)
+	if tests[0].Output != "first line\n</script><b>second</b>\n" || tests[1].Output != "" {
+		t.Errorf("Unexpected outputs %q and %q", tests[0].Output, tests[1].Output)
+	}
+	out := filepath.Join(dir, "report.html")
+	if err := writeFile(out, func(w io.Writer) error { return renderReport(w, tests) }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	if strings.Contains(page, "</script><b>") {
+		t.Errorf("Expected the test output escaped in the page")
+	}
+	for _, s := range []string{`"name":"test.go:1"`, `"blockType":"slow"`, `"output":"first line\n`, `id="min"`, chartJS[:100]} {
+		if !strings.Contains(page, s) {
+			t.Errorf("Expected %q in the page", s)
+		}
+	}
+}
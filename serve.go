@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wozniakjan/test_eval/compare"
+	"github.com/wozniakjan/test_eval/history"
+	"github.com/wozniakjan/test_eval/top"
+)
+
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "Listen address")
+	outs := fs.String("outs", "outs", "Folder with the analyze output folders")
+	dir := fs.String("dir", "history", "History folder")
+	maxUpload := fs.Int64("max-upload", defaultMaxUpload, "Maximum size in bytes of a log uploaded to /analyze")
+	maxLog := fs.Int64("max-log", defaultMaxLog, "Maximum size in bytes of an uploaded log after decompression")
+	timeout := fs.Duration("timeout", defaultUploadTimeout, "Timeout of reading an uploaded log, the processing of the read log is not interrupted")
+	fs.Parse(args)
+	h := newServer(*outs, history.Store{Dir: *dir})
+	h.maxUpload, h.maxLog, h.uploadTimeout = *maxUpload, *maxLog, *timeout
+	s := &http.Server{
+		Addr:              *addr,
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       *timeout,
+	}
+	log.Printf("serving %v and %v on %v", *outs, *dir, *addr)
+	return s.ListenAndServe()
+}
+
+// server browses the analyze output folders and the history store, it never
+// writes to them. Uploaded logs are analyzed in memory.
+type server struct {
+	outs    string
+	history history.Store
+	mux     *http.ServeMux
+
+	maxUpload     int64
+	maxLog        int64
+	uploadTimeout time.Duration
+}
+
+func newServer(outs string, store history.Store) *server {
+	s := &server{
+		outs:          outs,
+		history:       store,
+		mux:           http.NewServeMux(),
+		maxUpload:     defaultMaxUpload,
+		maxLog:        defaultMaxLog,
+		uploadTimeout: defaultUploadTimeout,
+	}
+	s.mux.HandleFunc("/", s.index)
+	s.mux.HandleFunc("/outs/", s.out)
+	s.mux.HandleFunc("/compare", s.compare)
+	s.mux.HandleFunc("/history/", s.job)
+	s.mux.HandleFunc("/analyze", s.analyze)
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	allowed, allow := r.Method == http.MethodGet || r.Method == http.MethodHead, "GET, HEAD"
+	if r.URL.Path == "/analyze" {
+		allowed, allow = allowed || r.Method == http.MethodPost, "GET, HEAD, POST"
+	}
+	if !allowed {
+		w.Header().Set("Allow", allow)
+		http.Error(w, "read-only", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// builds returns the analyze output folders with a stats.json.
+func (s *server) builds() ([]string, error) {
+	files, err := ioutil.ReadDir(s.outs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names := make([]string, 0)
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(s.outs, f.Name(), "stats.json")); f.IsDir() && err == nil {
+			names = append(names, f.Name())
+		}
+	}
+	return names, nil
+}
+
+// render writes the page to a buffer first so errors do not end up in a half
+// written page.
+func render(w http.ResponseWriter, contentType string, write func(w io.Writer) error) {
+	buf := &bytes.Buffer{}
+	if err := write(buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
+
+func page(w http.ResponseWriter, name string, data interface{}) {
+	render(w, "text/html; charset=utf-8", func(w io.Writer) error { return serveTemplates.ExecuteTemplate(w, name, data) })
+}
+
+func (s *server) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	builds, err := s.builds()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jobs, err := s.history.Jobs()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sort.Strings(jobs)
+	page(w, "index", struct{ Builds, Jobs []string }{builds, jobs})
+}
+
+// resultPattern matches the per test files written by analyze.
+const resultPattern = "[0-9][0-9][0-9][0-9]_*"
+
+// resultFiles returns the per test files written by analyze, slowest first.
+func resultFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, resultPattern))
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// out serves /outs/<build>/ and its views: chart, report, timeline,
+// stats.json and tests/<file>.
+func (s *server) out(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/outs/"), "/", 3)
+	if len(parts) < 2 || !history.ValidName(parts[0]) {
+		http.NotFound(w, r)
+		return
+	}
+	name, dir := parts[0], filepath.Join(s.outs, parts[0])
+	stats, err := readInput(filepath.Join(dir, "stats.json"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch view := parts[1]; {
+	case view == "" && len(parts) == 2:
+		summary, _ := ioutil.ReadFile(filepath.Join(dir, "summary.txt"))
+		tests, err := resultFiles(dir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		builds, _ := s.builds()
+		page(w, "build", struct {
+			Name    string
+			Summary string
+			Tests   []string
+			Builds  []string
+		}{name, string(summary), tests, builds})
+	case view == "chart":
+		render(w, "text/html; charset=utf-8", func(w io.Writer) error { return renderPage(w, stats, true) })
+	case view == "report":
+		render(w, "text/html; charset=utf-8", func(w io.Writer) error { return renderReport(w, reportTests(dir, stats)) })
+	case view == "timeline":
+		byNode := r.URL.Query().Get("nodes") != ""
+		s, _ := top.ReadSummary(filepath.Join(dir, "summary.json"))
+		render(w, "text/html; charset=utf-8", func(w io.Writer) error { return renderGantt(w, stats, s.Tests, byNode) })
+	case view == "stats.json":
+		http.ServeFile(w, r, filepath.Join(dir, "stats.json"))
+	case view == "tests" && len(parts) == 3 && history.ValidName(parts[2]):
+		if ok, _ := filepath.Match(resultPattern, parts[2]); !ok {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, parts[2]))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(data)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// compare serves /compare?old=<build>&new=<build>, as json with format=json.
+func (s *server) compare(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	old, new := q.Get("old"), q.Get("new")
+	if !history.ValidName(old) || !history.ValidName(new) {
+		http.Error(w, "old and new builds are required", http.StatusBadRequest)
+		return
+	}
+	load := func(name string) ([]top.Blocks, error) {
+		return compare.Load(filepath.Join(s.outs, name, "stats.json"), top.DefaultOptions())
+	}
+	o, err := load(old)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	n, err := load(new)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	deltas := compare.Compare(o, n)
+	if q.Get("format") == "json" {
+		render(w, "application/json", func(w io.Writer) error { return compare.WriteJSON(w, deltas) })
+		return
+	}
+	table := &bytes.Buffer{}
+	if err := compare.WriteText(table, deltas); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	page(w, "compare", struct{ Old, New, Table string }{old, new, table.String()})
+}
+
+type jobBuild struct {
+	history.Build
+	Time float64
+}
+
+// job serves /history/<job>/ with the builds of the job and
+// /history/<job>/<build> with the tests of a build.
+func (s *server) job(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/history/"), "/", 2)
+	if len(parts) < 2 || !history.ValidName(parts[0]) {
+		http.NotFound(w, r)
+		return
+	}
+	job := parts[0]
+	if parts[1] != "" {
+		id, err := strconv.Atoi(parts[1])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		b, err := s.history.Get(job, id)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		sort.SliceStable(b.Tests, func(i, j int) bool { return b.Tests[i].Time > b.Tests[j].Time })
+		page(w, "history build", b)
+		return
+	}
+	builds, err := s.history.Last(job, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(builds) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	jb := make([]jobBuild, len(builds))
+	for i, b := range builds {
+		jb[len(builds)-1-i].Build = b
+		for _, t := range b.Tests {
+			jb[len(builds)-1-i].Time += t.Time
+		}
+	}
+	page(w, "history job", struct {
+		Job    string
+		Builds []jobBuild
+	}{job, jb})
+}
+
+var serveTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"seconds": formatSeconds,
+}).Parse(`
+{{define "head"}}<!DOCTYPE HTML>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+	body { font-family: sans-serif; margin: 1em; }
+	table { border-collapse: collapse; }
+	th, td { padding: 0.2em 0.5em; border-bottom: 1px solid #ddd; text-align: left; }
+	td.time { text-align: right; }
+</style>
+</head>
+<body>
+<p><a href="/">test_eval</a></p>
+<h1>{{.}}</h1>
+{{end}}
+{{define "foot"}}</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "head" "Builds"}}
+<p><a href="/analyze">Analyze a log</a></p>
+<ul>
+{{range .Builds}}<li><a href="/outs/{{.}}/">{{.}}</a></li>
+{{else}}<li>no analyze output</li>
+{{end}}</ul>
+<h2>History</h2>
+<ul>
+{{range .Jobs}}<li><a href="/history/{{.}}/">{{.}}</a></li>
+{{else}}<li>no history</li>
+{{end}}</ul>
+{{template "foot"}}{{end}}
+
+{{define "build"}}{{template "head" .Name}}
+<p>
+<a href="chart">chart</a> |
+<a href="report">report</a> |
+<a href="timeline">timeline</a> |
+<a href="timeline?nodes=1">timeline by node</a> |
+<a href="stats.json">stats.json</a>
+</p>
+<form action="/compare">
+<input type="hidden" name="new" value="{{.Name}}">
+Compare with <select name="old">{{range .Builds}}<option>{{.}}</option>{{end}}</select>
+<input type="submit" value="compare">
+</form>
+<pre>{{.Summary}}</pre>
+<h2>Tests</h2>
+<ul>
+{{range .Tests}}<li><a href="tests/{{.}}">{{.}}</a></li>
+{{end}}</ul>
+{{template "foot"}}{{end}}
+
+{{define "compare"}}{{template "head" (printf "%v → %v" .Old .New)}}
+<p><a href="/compare?old={{.Old}}&amp;new={{.New}}&amp;format=json">json</a></p>
+<pre>{{.Table}}</pre>
+{{template "foot"}}{{end}}
+
+{{define "analyze"}}{{template "head" "Analyze a log"}}
+<form action="/analyze" method="post" enctype="multipart/form-data">
+<p><label>Threshold <input name="t" type="number" min="0" step="any" value="120">s</label></p>
+<p><label>Window size <input name="w" type="number" min="1" value="5"></label></p>
+<p><label>Detection <select name="detect"><option>window</option><option>gap</option></select></label></p>
+<p><label>Threshold strategy <select name="strategy"><option>fixed</option><option>relative</option><option>percentile</option></select></label>
+<label>relative <input name="relative" type="number" min="0" max="1" step="any" value="0.2"></label>
+<label>percentile <input name="percentile" type="number" min="0" max="100" step="any" value="99"></label></p>
+<p><label>Slowest tests <input name="c" type="number" value="5"></label> all when less than 1</p>
+<p><label>Format <select name="format"><option>html</option><option>json</option></select></label></p>
+<p><label>Console log, plain or gzipped <input name="log" type="file" required></label></p>
+<p><input type="submit" value="analyze"></p>
+</form>
+{{template "foot"}}{{end}}
+
+{{define "history job"}}{{template "head" .Job}}
+<table>
+<tr><th>Build</th><th>Started</th><th>Tests</th><th>Time (s)</th></tr>
+{{range .Builds}}<tr><td><a href="{{.ID}}">{{.ID}}</a></td><td>{{.Started.Format "2006-01-02 15:04:05"}}</td><td class="time">{{len .Tests}}</td><td class="time">{{seconds .Time}}</td></tr>
+{{end}}</table>
+{{template "foot"}}{{end}}
+
+{{define "history build"}}{{template "head" (printf "%v #%v" .Job .ID)}}
+<table>
+<tr><th>Time (s)</th><th>Test</th><th>Slow windows</th></tr>
+{{range .Tests}}<tr><td class="time">{{seconds .Time}}</td><td>{{.Name}}</td><td class="time">{{len .Windows}}</td></tr>
+{{end}}</table>
+{{template "foot"}}{{end}}
+`))
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wozniakjan/test_eval/history"
+	"github.com/wozniakjan/test_eval/top"
+)
+
+func TestServe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	for name, d := range map[string]float64{"old": 10, "new": 30} {
+		out := filepath.Join(dir, "outs", name)
+		if err := os.MkdirAll(out, 0777); err != nil {
+			t.Fatal(err)
+		}
+		stats := []top.Blocks{{
+			Name: "test.go:1", Time: d, Start: start, End: start.Add(top.Seconds(d)), Status: top.Passed,
+			Blocks: []top.Block{{Lines: []string{"line"}, Start: 0, End: d, BlockType: "slow"}},
+		}}
+		data, err := json.Marshal(stats)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := map[string]string{
+			"stats.json":        string(data),
+			"summary.txt":       "tests: 1",
+			"0001_30_test.go:1": "time: 30s\n\n\nEntire output:\n<b>output</b>\n",
+		}
+		for f, content := range files {
+			if err := ioutil.WriteFile(filepath.Join(out, f), []byte(content), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	store := history.Store{Dir: filepath.Join(dir, "history")}
+	if err := store.Add(history.Build{Job: "myjob", ID: 7, Started: start, Tests: []history.Test{{Name: "<slow>", Time: 12}}}); err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(newServer(filepath.Join(dir, "outs"), store))
+	defer s.Close()
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   []string
+	}{
+		{"GET", "/", 200, []string{`href="/outs/new/"`, `href="/outs/old/"`, `href="/history/myjob/"`}},
+		{"GET", "/outs/new/", 200, []string{"tests: 1", `href="tests/0001_30_test.go:1"`, `href="timeline"`, "<option>old</option>"}},
+		{"GET", "/outs/new/chart", 200, []string{"test.go:1", chartJS[:100]}},
+		{"GET", "/outs/new/report", 200, []string{`"output":"\u003cb\u003eoutput`}},
+		{"GET", "/outs/new/timeline?nodes=1", 200, []string{"<svg", "node 0"}},
+		{"GET", "/outs/new/stats.json", 200, []string{`"name":"test.go:1"`}},
+		{"GET", "/outs/new/tests/0001_30_test.go:1", 200, []string{"<b>output</b>"}},
+		{"GET", "/outs/new/tests/summary.txt", 404, nil},
+		{"GET", "/outs/new/tests/..", 404, nil},
+		{"GET", "/outs/missing/", 404, nil},
+		{"GET", "/outs/new/tests/..%2F..%2F..%2F..%2Fetc%2Fpasswd", 404, nil},
+		{"GET", "/compare?old=old&new=new", 200, []string{"test.go:1", "10s -&gt; 30s"}},
+		{"GET", "/compare?old=old&new=new&format=json", 200, []string{`"name"`}},
+		{"GET", "/compare?old=old", 400, nil},
+		{"GET", "/history/myjob/", 200, []string{`href="7"`, "2018-01-02 03:04:05", "12"}},
+		{"GET", "/history/myjob/7", 200, []string{"&lt;slow&gt;"}},
+		{"GET", "/history/myjob/8", 404, nil},
+		{"GET", "/history/other/", 404, nil},
+		{"POST", "/", 405, nil},
+		{"DELETE", "/outs/new/stats.json", 405, nil},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, s.URL+test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.code {
+			t.Errorf("%v %v: Expected %v, got %v", test.method, test.path, test.code, resp.StatusCode)
+			continue
+		}
+		for _, b := range test.body {
+			if !strings.Contains(string(body), b) {
+				t.Errorf("%v %v: Expected %q in %s", test.method, test.path, b, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package top
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var commandRegexp = regexp.MustCompile(`INFO: Running '(?:\S*/)?oc (.*)'\s*$`)
+
+// commandGroups are the oc commands whose verb is the first two words, e.g.
+// `oc adm policy` or `oc set env`.
+var commandGroups = map[string]bool{"adm": true, "set": true, "rollout": true, "policy": true, "secrets": true}
+
+// valueFlags are the short flags taking the next argument as value.
+var valueFlags = map[string]bool{"-o": true, "-l": true, "-p": true}
+
+// fileVerbs are the commands whose -f is the file to read, it follows the
+// logs of `oc logs -f`.
+var fileVerbs = map[string]bool{"create": true, "apply": true, "new-app": true, "process": true}
+
+// Command is an oc invocation in the test output. Start is in seconds since
+// the first timestamped line of the test and Time is the time until the next
+// timestamped line, zero for the last one.
+type Command struct {
+	Verb      string  `json:"verb"`
+	Resource  string  `json:"resource,omitempty"`
+	Namespace string  `json:"namespace,omitempty"`
+	Start     float64 `json:"start"`
+	Time      float64 `json:"time"`
+	Line      string  `json:"line"`
+}
+
+// parseCommand returns the oc command run on the line, ok is false for other
+// lines.
+func parseCommand(line string) (c Command, ok bool) {
+	m := commandRegexp.FindStringSubmatch(line)
+	if len(m) < 2 {
+		return c, false
+	}
+	c.Line = line
+	positional := make([]string, 0, 3)
+	args := strings.Fields(m[1])
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case strings.HasPrefix(a, "--namespace="):
+			c.Namespace = strings.TrimPrefix(a, "--namespace=")
+		case a == "-n" && i+1 < len(args):
+			c.Namespace = args[i+1]
+			i++
+		case strings.HasPrefix(a, "-n="):
+			c.Namespace = strings.TrimPrefix(a, "-n=")
+		case valueFlags[a] || a == "-f" && len(positional) > 0 && fileVerbs[positional[0]]:
+			i++
+		case strings.HasPrefix(a, "-"):
+		default:
+			positional = append(positional, a)
+		}
+	}
+	if len(positional) > 1 && commandGroups[positional[0]] {
+		positional = append([]string{positional[0] + " " + positional[1]}, positional[2:]...)
+	}
+	if len(positional) > 0 {
+		c.Verb = positional[0]
+	}
+	if len(positional) > 1 {
+		c.Resource = positional[1]
+	}
+	return c, c.Verb != ""
+}
+
+// commands finds the oc commands in the output of a test.
+func commands(lines []Line) []Command {
+	cmds := make([]Command, 0)
+	var first, last time.Time
+	running := -1
+	for _, l := range lines {
+		if !l.HasTime {
+			continue
+		}
+		t := l.Time
+		if first.IsZero() {
+			first = t
+		}
+		if running >= 0 {
+			cmds[running].Time = duration(last, t).Seconds()
+			running = -1
+		}
+		last = t
+		if c, ok := parseCommand(l.Line); ok {
+			c.Start = duration(first, t).Seconds()
+			running = len(cmds)
+			cmds = append(cmds, c)
+		}
+	}
+	return cmds
+}
+
+// CommandStats is the time taken by all the calls of an oc command in seconds.
+type CommandStats struct {
+	Command string  `json:"command"`
+	Calls   int     `json:"calls"`
+	Time    float64 `json:"time"`
+	P95     float64 `json:"p95"`
+}
+
+// commandStats aggregates the commands of all the tests by verb, the most
+// time consuming first.
+func commandStats(tests []Test) []CommandStats {
+	times := make(map[string][]float64)
+	for _, t := range tests {
+		for _, c := range t.Commands {
+			name := "oc " + c.Verb
+			times[name] = append(times[name], c.Time)
+		}
+	}
+	stats := make([]CommandStats, 0, len(times))
+	for name, d := range times {
+		sort.Float64s(d)
+		s := CommandStats{Command: name, Calls: len(d)}
+		for _, v := range d {
+			s.Time += v
+		}
+		// nearest rank
+		s.P95 = d[int(math.Ceil(0.95*float64(len(d))))-1]
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Time != stats[j].Time {
+			return stats[i].Time > stats[j].Time
+		}
+		return stats[i].Command < stats[j].Command
+	})
+	return stats
+}
+
+func writeCommandStats(w io.Writer, stats []CommandStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "commands:\n")
+	for _, s := range stats {
+		if _, err := fmt.Fprintf(w, "  %v: %v calls, %v total, p95 %v\n", s.Command, s.Calls, Seconds(s.Time).Round(time.Second), Seconds(s.P95).Round(time.Second)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
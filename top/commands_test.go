@@ -0,0 +1,84 @@
+package top
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		line      string
+		ok        bool
+		verb      string
+		resource  string
+		namespace string
+	}{
+		{"Apr  3 11:48:35.747: INFO: Running 'oc new-app --config=/tmp/user.kubeconfig --namespace=ns1 -f /tmp/pipeline.yaml'", true, "new-app", "", "ns1"},
+		{"Apr  3 11:48:39.007: INFO: Running 'oc start-build --config=/tmp/user.kubeconfig --namespace=ns1 openshift-jee-sample -o=name'", true, "start-build", "openshift-jee-sample", "ns1"},
+		{"Apr  3 11:57:42.545: INFO: Running 'oc delete -n ns2 bc openshift-jee-sample-docker'", true, "delete", "bc", "ns2"},
+		{"Apr  3 11:57:43.000: INFO: Running 'oc adm policy add-role-to-user edit bob'", true, "adm policy", "add-role-to-user", ""},
+		{"Apr  3 11:57:44.000: INFO: Running '/usr/bin/oc get pods'", true, "get", "pods", ""},
+		{"Apr  3 11:57:44.100: INFO: Running 'oc logs -f bc/foo -n ns3'", true, "logs", "bc/foo", "ns3"},
+		{"Apr  3 11:57:44.200: INFO: Running 'oc process -f /tmp/template.yaml -p NAME=foo -o yaml'", true, "process", "", ""},
+		{"Apr  3 11:57:44.300: INFO: Running 'oc get -l app=foo pods -w'", true, "get", "pods", ""},
+		{"Apr  3 11:57:45.000: INFO: Running AfterSuite actions", false, "", "", ""},
+	}
+	for _, tt := range tests {
+		c, ok := parseCommand(tt.line)
+		if ok != tt.ok || c.Verb != tt.verb || c.Resource != tt.resource || c.Namespace != tt.namespace {
+			t.Errorf("Expected %v %q %q %q, got %v %q %q %q", tt.ok, tt.verb, tt.resource, tt.namespace, ok, c.Verb, c.Resource, c.Namespace)
+		}
+	}
+}
+
+func TestCommandStats(t *testing.T) {
+	log := `------------------------------
+Dec 31 23:59:50.000: INFO: Running 'oc start-build foo'
+Dec 31 23:59:59.000: INFO: Waiting for foo-1 to complete
+Jan  1 00:00:30.000: INFO: Running 'oc start-build bar'
+Jan  1 00:00:31.500: INFO: Running 'oc delete bc foo'
+/go/src/github.com/openshift/origin/test/extended/builds/a.go:1
+•
+`
+	r, err := Parse(strings.NewReader(log), DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmds := r.Tests[0].Commands
+	expects := []struct {
+		start, time float64
+	}{{0, 9}, {40, 1.5}, {41.5, 0}}
+	if len(cmds) != len(expects) {
+		t.Fatalf("Expected %v commands, got %v", len(expects), cmds)
+	}
+	for i, e := range expects {
+		if cmds[i].Start != e.start || cmds[i].Time != e.time {
+			t.Errorf("Expected command %v at %v taking %v, got %v taking %v", i, e.start, e.time, cmds[i].Start, cmds[i].Time)
+		}
+	}
+	stats := r.Summary().Commands
+	if len(stats) != 2 || stats[0].Command != "oc start-build" || stats[0].Calls != 2 || stats[0].Time != 10.5 || stats[0].P95 != 9 {
+		t.Errorf("Expected oc start-build first with 2 calls taking 10.5s, got %+v", stats)
+	}
+}
+
+func TestCommandsJump(t *testing.T) {
+	log := `------------------------------
+Apr  3 12:00:00.000: INFO: Running 'oc get pods'
+Apr  3 11:59:59.000: INFO: Running 'oc delete bc foo'
+Apr  3 12:00:05.000: INFO: Done
+/go/src/github.com/openshift/origin/test/extended/builds/a.go:1
+•
+`
+	r, err := Parse(strings.NewReader(log), DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmds := r.Tests[0].Commands
+	if len(cmds) != 2 || cmds[0].Time != 0 || cmds[1].Start != 0 || cmds[1].Time != 6 {
+		t.Errorf("Expected the jump back to take 0s, got %+v", cmds)
+	}
+	if len(r.Jumps) != 1 {
+		t.Errorf("Expected 1 jump, got %v", r.Jumps)
+	}
+}
@@ -0,0 +1,87 @@
+package top
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// ginkgoSuite is a suite of the ginkgo --json-report output.
+type ginkgoSuite struct {
+	SuiteDescription string
+	StartTime        time.Time
+	SpecReports      []ginkgoSpec
+}
+
+type ginkgoSpec struct {
+	ContainerHierarchyTexts    []string
+	LeafNodeType               string
+	LeafNodeText               string
+	State                      string
+	StartTime                  time.Time
+	RunTime                    time.Duration
+	CapturedGinkgoWriterOutput string
+}
+
+func (s ginkgoSpec) text() string {
+	texts := append([]string{}, s.ContainerHierarchyTexts...)
+	if s.LeafNodeText != "" {
+		texts = append(texts, s.LeafNodeText)
+	}
+	if len(texts) == 0 {
+		return "[" + s.LeafNodeType + "]"
+	}
+	return strings.Join(texts, " ")
+}
+
+func (s ginkgoSpec) status() string {
+	switch s.State {
+	case "passed":
+		return Passed
+	case "skipped":
+		return Skipped
+	case "pending":
+		return Pending
+	}
+	// failed, aborted, panicked, interrupted, timedout
+	return Failed
+}
+
+// ParseGinkgo reads a ginkgo JSON report, written with --json-report, and
+// returns all its specs with the GinkgoWriter output as the test output and
+// the spec text as the test name.
+func ParseGinkgo(r io.Reader, opts Options) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	suites := make([]ginkgoSuite, 0)
+	if err := json.NewDecoder(r).Decode(&suites); err != nil {
+		return nil, err
+	}
+	rules, _ := opts.phaseRules()
+	phases := newPhaseTracker(rules)
+	report := &Report{Tests: make([]Test, 0)}
+	start := opts.Start
+	for _, suite := range suites {
+		if start.IsZero() {
+			start = suite.StartTime
+		}
+		for _, s := range suite.SpecReports {
+			t := Test{Spec: s.text(), Status: s.status(), Time: s.RunTime.Seconds(), Start: s.StartTime, Lines: make([]string, 0)}
+			if out := strings.TrimRight(s.CapturedGinkgoWriterOutput, "\n"); out != "" {
+				for _, l := range strings.Split(out, "\n") {
+					if !ignore(l) {
+						phases.parse(l)
+						t.Lines = append(t.Lines, l)
+					}
+				}
+			}
+			t.Phases = phases.done()
+			t.Slow = t.Time >= slowSpecThreshold
+			report.Tests = append(report.Tests, t)
+		}
+	}
+	report.process(opts, start)
+	return report, nil
+}
@@ -0,0 +1,73 @@
+package top
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var ginkgoReport = `[{
+  "SuiteDescription": "Extended",
+  "StartTime": "2018-12-31T23:50:00Z",
+  "SpecReports": [
+    {
+      "LeafNodeType": "SynchronizedBeforeSuite",
+      "State": "passed",
+      "RunTime": 2500000000
+    },
+    {
+      "ContainerHierarchyTexts": ["[sig-builds]", "pipeline"],
+      "LeafNodeType": "It",
+      "LeafNodeText": "should build",
+      "State": "passed",
+      "RunTime": 552000000000,
+      "CapturedGinkgoWriterOutput": "Dec 31 23:59:00.000: INFO: Running 'oc start-build'\nJan  1 00:08:00.000: INFO: Done waiting\n"
+    },
+    {
+      "ContainerHierarchyTexts": ["[sig-builds]", "digest"],
+      "LeafNodeType": "It",
+      "LeafNodeText": "should fail",
+      "State": "timedout",
+      "RunTime": 7000000000
+    },
+    {
+      "ContainerHierarchyTexts": ["[sig-builds]"],
+      "LeafNodeType": "It",
+      "LeafNodeText": "later",
+      "State": "pending"
+    }
+  ]
+}]`
+
+func TestParseGinkgo(t *testing.T) {
+	r, err := ParseGinkgo(strings.NewReader(ginkgoReport), DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expects := []struct {
+		name   string
+		status string
+		time   float64
+	}{
+		{"[SynchronizedBeforeSuite]", Passed, 2.5},
+		{"[sig-builds] pipeline should build", Passed, 552},
+		{"[sig-builds] digest should fail", Failed, 7},
+		{"[sig-builds] later", Pending, 0},
+	}
+	if len(r.Tests) != len(expects) {
+		t.Fatalf("Expected %v tests, got %v", len(expects), len(r.Tests))
+	}
+	for i, e := range expects {
+		tc := r.Tests[i]
+		if tc.Name() != e.name || tc.Status != e.status || tc.Time != e.time {
+			t.Errorf("Expected %v %v %v, got %v %v %v", e.name, e.status, e.time, tc.Name(), tc.Status, tc.Time)
+		}
+	}
+	w := r.Tests[1].Windows
+	if len(w) != 1 || w[0].Time() != 9*time.Minute {
+		t.Errorf("Expected a 9m window over new year, got %v", w)
+	}
+	if _, err := ParseGinkgo(strings.NewReader("{"), DefaultOptions()); err == nil {
+		t.Errorf("Expected error for malformed report")
+	}
+}
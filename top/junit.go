@@ -0,0 +1,186 @@
+package top
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var codeLocationRegexp = regexp.MustCompile(`\.go:[0-9]+$`)
+
+type junitCase struct {
+	Name      string    `xml:"name,attr"`
+	Time      string    `xml:"time,attr"`
+	Failure   *struct{} `xml:"failure"`
+	Error     *struct{} `xml:"error"`
+	Skipped   *struct{} `xml:"skipped"`
+	SystemOut string    `xml:"system-out"`
+}
+
+func (c junitCase) status() string {
+	switch {
+	case c.Failure != nil || c.Error != nil:
+		return Failed
+	case c.Skipped != nil:
+		return Skipped
+	}
+	return Passed
+}
+
+// ParseJUnit reads a JUnit XML report and returns all its test cases with
+// their system-out as the test output.
+func ParseJUnit(r io.Reader, opts Options) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	report, start, err := parseJUnit(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	report.process(opts, start)
+	return report, nil
+}
+
+// ParseJUnits reads the JUnit XML reports of a suite, e.g. one per parallel
+// node, into one report. Every report has its own timeline and the thresholds
+// are computed over all of them.
+func ParseJUnits(reports []io.Reader, opts Options) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	parsed := make([]node, 0, len(reports))
+	for i, r := range reports {
+		report, start, err := parseJUnit(r, opts)
+		if err != nil {
+			return nil, fmt.Errorf("report %v: %v", i+1, err)
+		}
+		parsed = append(parsed, node{report, start})
+	}
+	return processNodes(parsed, opts), nil
+}
+
+// parseJUnit reads the test cases of a JUnit report without processing them
+// and returns the start of the report.
+func parseJUnit(r io.Reader, opts Options) (*Report, time.Time, error) {
+	rules, _ := opts.phaseRules()
+	phases := newPhaseTracker(rules)
+	report := &Report{Tests: make([]Test, 0)}
+	start := opts.Start
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "testcase" {
+			continue
+		}
+		c := junitCase{}
+		if err := d.DecodeElement(&c, &se); err != nil {
+			return nil, time.Time{}, err
+		}
+		t := Test{Spec: c.Name, Status: c.status(), Lines: make([]string, 0)}
+		if c.Time != "" {
+			if t.Time, err = strconv.ParseFloat(c.Time, 64); err != nil {
+				return nil, time.Time{}, fmt.Errorf("test case %q: %v", c.Name, err)
+			}
+		}
+		if out := strings.TrimRight(c.SystemOut, "\n"); out != "" {
+			for _, l := range strings.Split(out, "\n") {
+				if ignore(l) {
+					continue
+				}
+				if start.IsZero() {
+					start, _ = fullTime(l)
+				}
+				phases.parse(l)
+				t.Lines = append(t.Lines, l)
+			}
+		}
+		t.Phases = phases.done()
+		t.Slow = t.Time >= slowSpecThreshold
+		report.Tests = append(report.Tests, t)
+	}
+	return report, start, nil
+}
+
+var specEndPrefixes = []string{"STEP:", "[BeforeEach]", "[JustBeforeEach]", "[It]", "[AfterEach]"}
+
+// consoleSpec returns the spec text ginkgo prints at the start of a test, the
+// component texts each followed by its code location, joined as in the JUnit
+// test case name.
+func consoleSpec(lines []string) string {
+	parts := make([]string, 0)
+	located := false
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "-----") {
+			continue
+		}
+		if l == "" || timeRegexp.MatchString(l) || hasPrefix(l, specEndPrefixes) {
+			break
+		}
+		if codeLocationRegexp.MatchString(l) {
+			located = true
+			continue
+		}
+		parts = append(parts, strings.Join(strings.Fields(l), " "))
+	}
+	if !located {
+		return ""
+	}
+	return strings.Join(parts, " ")
+}
+
+func hasPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Link replaces the output of the tests with the output of the matching tests
+// in the console log, keeping their names, durations and statuses. The
+// console tests without a match are added to the report. The thresholds are
+// the console ones when any test matches.
+func (r *Report) Link(console *Report) {
+	bySpec := make(map[string][]int)
+	for i, t := range console.Tests {
+		if spec := consoleSpec(t.Lines); spec != "" {
+			bySpec[spec] = append(bySpec[spec], i)
+		}
+	}
+	linked := make(map[int]bool)
+	for i := range r.Tests {
+		t := &r.Tests[i]
+		key := strings.Join(strings.Fields(t.Spec), " ")
+		idx := bySpec[key]
+		if key == "" || len(idx) == 0 {
+			continue
+		}
+		ct := console.Tests[idx[0]]
+		bySpec[key] = idx[1:]
+		linked[idx[0]] = true
+		t.Node, t.Start, t.Threshold, t.Lines, t.Phases, t.Commands, t.Waits, t.Windows, t.Blocks = ct.Node, ct.Start, ct.Threshold, ct.Lines, ct.Phases, ct.Commands, ct.Waits, ct.Windows, ct.Blocks
+		t.fillStats()
+	}
+	for i, t := range console.Tests {
+		if !linked[i] {
+			r.Tests = append(r.Tests, t)
+		}
+	}
+	r.Jumps = append(r.Jumps, console.Jumps...)
+	if len(linked) > 0 {
+		r.Thresholds = console.Thresholds
+	}
+}
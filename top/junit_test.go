@@ -0,0 +1,110 @@
+package top
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var junit = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="Extended" tests="3" failures="1" time="560">
+    <testcase name="[builds] pipeline should build" classname="Extended" time="552.5">
+      <system-out>Apr  3 11:48:35.747: INFO: Running &apos;oc new-app&apos;
+Apr  3 11:57:41.804: INFO: Done waiting
+</system-out>
+    </testcase>
+    <testcase name="[builds] digest should fail" classname="Extended" time="7">
+      <failure message="timeout">timed out</failure>
+    </testcase>
+    <testcase name="[builds] skipped" classname="Extended" time="0">
+      <skipped></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>`
+
+func TestParseJUnit(t *testing.T) {
+	r, err := ParseJUnit(strings.NewReader(junit), DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expects := []struct {
+		name   string
+		status string
+		time   float64
+		lines  int
+	}{
+		{"[builds] pipeline should build", Passed, 552.5, 2},
+		{"[builds] digest should fail", Failed, 7, 0},
+		{"[builds] skipped", Skipped, 0, 0},
+	}
+	if len(r.Tests) != len(expects) {
+		t.Fatalf("Expected %v tests, got %v", len(expects), len(r.Tests))
+	}
+	for i, e := range expects {
+		tc := r.Tests[i]
+		if tc.Name() != e.name || tc.Status != e.status || tc.Time != e.time || len(tc.Lines) != e.lines {
+			t.Errorf("Expected %v %v %v %v lines, got %v %v %v %v lines", e.name, e.status, e.time, e.lines, tc.Name(), tc.Status, tc.Time, len(tc.Lines))
+		}
+	}
+	if w := r.Tests[0].Windows; len(w) != 1 {
+		t.Errorf("Expected a slow window from system-out, got %v", w)
+	}
+}
+
+func TestParseJUnits(t *testing.T) {
+	report := func(name, out string) io.Reader {
+		return strings.NewReader(`<testsuite><testcase name="` + name + `" time="20"><system-out>` + out + `</system-out></testcase></testsuite>`)
+	}
+	reports := []io.Reader{
+		report("first", "Apr  3 11:00:00.000: INFO: a\nApr  3 11:00:10.000: INFO: b\nApr  3 11:00:20.000: INFO: c\n"),
+		report("second", "Apr  3 11:00:00.000: INFO: a\nApr  3 11:01:40.000: INFO: b\n"),
+	}
+	r, err := ParseJUnits(reports, Options{WindowSize: 2, Strategy: PercentileThreshold, Percentile: 50})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// p50 of the gaps 10s, 10s and 100s of both reports
+	if th := r.Thresholds; th.Gaps != 3 || th.Threshold != 10 {
+		t.Errorf("Expected p50 of 3 gaps, 10s, got %+v", th)
+	}
+	if len(r.Tests) != 2 || r.Tests[0].Threshold != 10*time.Second || r.Tests[1].Threshold != 10*time.Second || len(r.Tests[1].Windows) != 1 {
+		t.Errorf("Expected both tests with the 10s threshold, got %+v", r.Tests)
+	}
+	if len(r.Jumps) != 0 {
+		t.Errorf("Expected a timeline per report, got jumps %v", r.Jumps)
+	}
+	if _, err := ParseJUnits([]io.Reader{strings.NewReader(`<testcase name="x" time="abc"></testcase>`)}, DefaultOptions()); err == nil || !strings.Contains(err.Error(), "report 1") {
+		t.Errorf("Expected the report number in the error, got %v", err)
+	}
+}
+
+func TestLink(t *testing.T) {
+	console := `------------------------------
+[builds] digest
+/go/src/github.com/openshift/origin/test/extended/builds/digest.go:20
+  should fail
+  /go/src/github.com/openshift/origin/test/extended/builds/digest.go:65
+Apr  3 11:58:00.000: INFO: Running 'oc create'
+Apr  3 12:01:00.000: INFO: Running 'oc delete'
+
+• [SLOW TEST:180.000 seconds]
+`
+	r, err := ParseJUnit(strings.NewReader(junit), DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, err := Parse(strings.NewReader(console), DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Link(c)
+	if len(r.Tests) != 3 {
+		t.Fatalf("Expected console test linked to the JUnit one, got %v tests", len(r.Tests))
+	}
+	linked := r.Tests[1]
+	if linked.Status != Failed || linked.Time != 7 || len(linked.Windows) != 1 || linked.Blocks.Name != "[builds] digest should fail" {
+		t.Errorf("Expected failed test with the console window, got %v %v %v %v", linked.Status, linked.Time, linked.Windows, linked.Blocks.Name)
+	}
+}
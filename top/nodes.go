@@ -0,0 +1,107 @@
+package top
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nodeRegexp matches the [N] prefix of the lines ginkgo -stream prints for
+// parallel node N.
+var nodeRegexp = regexp.MustCompile(`^\[([0-9]+)\] ?(.*)$`)
+
+// ParseNodes parses the logs of parallel ginkgo nodes, node i+1 reading from
+// nodes[i]. Every node has its own timeline.
+func ParseNodes(nodes []io.Reader, opts Options) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	parsed := make([]node, 0, len(nodes))
+	for i, r := range nodes {
+		n, err := parseNode(r, i+1, opts)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, n)
+	}
+	return processNodes(parsed, opts), nil
+}
+
+// node is the parsed log of a parallel node.
+type node struct {
+	*Report
+	start time.Time
+}
+
+func parseNode(r io.Reader, n int, opts Options) (node, error) {
+	nr, start, err := parse(r, opts)
+	if err != nil {
+		return node{}, err
+	}
+	for i := range nr.Tests {
+		nr.Tests[i].Node = n
+	}
+	return node{nr, start}, nil
+}
+
+// processNodes processes the nodes, each with its own timeline and the
+// thresholds of all the nodes, and merges them into one report.
+func processNodes(nodes []node, opts Options) *Report {
+	all := make([]Test, 0)
+	for _, n := range nodes {
+		n.normalize(n.start)
+		all = append(all, n.Tests...)
+	}
+	report := &Report{Tests: make([]Test, 0), Thresholds: newThresholds(opts, all)}
+	for _, n := range nodes {
+		n.Thresholds = report.Thresholds
+		n.processTests(opts)
+		for i := range n.Tests {
+			n.Tests[i].Blocks.Node = n.Tests[i].Node
+		}
+		report.Tests = append(report.Tests, n.Tests...)
+		report.Jumps = append(report.Jumps, n.Jumps...)
+	}
+	return report
+}
+
+// ParseStream splits a log of parallel ginkgo nodes by the [N] line prefixes
+// and parses the output of each node separately. The lines without a prefix
+// belong to node 0.
+func ParseStream(r io.Reader, opts Options) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	streams := make(map[int][]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		n := 0
+		if m := nodeRegexp.FindStringSubmatch(line); len(m) > 2 {
+			n, _ = strconv.Atoi(m[1])
+			line = m[2]
+		}
+		streams[n] = append(streams[n], line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(streams))
+	for n := range streams {
+		ids = append(ids, n)
+	}
+	sort.Ints(ids)
+	parsed := make([]node, 0, len(ids))
+	for _, id := range ids {
+		n, err := parseNode(strings.NewReader(strings.Join(streams[id], "\n")), id, opts)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, n)
+	}
+	return processNodes(parsed, opts), nil
+}
@@ -0,0 +1,65 @@
+package top
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseStream(t *testing.T) {
+	log := `[1] ------------------------------
+[1] /go/src/github.com/openshift/origin/test/extended/builds/pipeline.go:437
+[2] ------------------------------
+[2] /go/src/github.com/openshift/origin/test/extended/builds/digest.go:65
+[1] Apr  3 11:48:35.000: INFO: Running 'oc start-build'
+[2] Apr  3 11:48:36.000: INFO: Running 'oc create'
+[2] Apr  3 11:48:40.000: INFO: Running 'oc delete'
+[2] • Failure [5.000 seconds]
+[1] Apr  3 11:57:35.000: INFO: Done waiting
+[1] • [SLOW TEST:540.000 seconds]
+Ran 2 of 2 Specs
+`
+	r, err := ParseStream(strings.NewReader(log), DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.Tests) != 2 {
+		t.Fatalf("Expected 2 tests, got %v", len(r.Tests))
+	}
+	expects := []struct {
+		name  string
+		node  int
+		lines int
+		slow  int
+	}{
+		{"/test/extended/builds/pipeline.go:437", 1, 4, 1},
+		{"/test/extended/builds/digest.go:65", 2, 4, 0},
+	}
+	for i, e := range expects {
+		tc := r.Tests[i]
+		if tc.Name() != e.name || tc.Node != e.node || tc.Blocks.Node != e.node || len(tc.Lines) != e.lines || len(tc.Windows) != e.slow {
+			t.Errorf("Expected %v on node %v with %v lines and %v windows, got %v on node %v with %v lines and %v windows",
+				e.name, e.node, e.lines, e.slow, tc.Name(), tc.Node, len(tc.Lines), len(tc.Windows))
+		}
+	}
+	if len(r.Jumps) != 0 {
+		t.Errorf("Expected no jumps within the nodes, got %v", r.Jumps)
+	}
+}
+
+func TestParseNodes(t *testing.T) {
+	nodes := []io.Reader{
+		strings.NewReader("------------------------------\nApr  3 11:48:35.000: INFO: a\n•\n"),
+		strings.NewReader("------------------------------\nApr  3 11:48:30.000: INFO: b\nApr  3 11:48:31.000: INFO: c\n•\n"),
+	}
+	r, err := ParseNodes(nodes, DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.Tests) != 2 || r.Tests[0].Node != 1 || r.Tests[1].Node != 2 || r.Tests[1].Time != 1 {
+		t.Errorf("Unexpected tests %v", r.Tests)
+	}
+	if len(r.Jumps) != 0 {
+		t.Errorf("Expected separate timelines per node, got jumps %v", r.Jumps)
+	}
+}
@@ -0,0 +1,236 @@
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+var dockerTime = `^([0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]*)?Z) `
+
+// PhaseRule describes a phase of a test, e.g. a docker build, by the regular
+// expressions of its first and last line. The first group of both expressions
+// is the timestamp in TimeFormat, a time.Parse layout.
+type PhaseRule struct {
+	Name       string `json:"name"`
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	TimeFormat string `json:"timeFormat"`
+}
+
+// DefaultPhaseRules returns the docker build and push rules.
+func DefaultPhaseRules() []PhaseRule {
+	return []PhaseRule{
+		{"docker build", dockerTime + `Step 1/`, dockerTime + `Successfully built`, time.RFC3339Nano},
+		{"docker push", dockerTime + `Pushing image`, dockerTime + `Push successful`, time.RFC3339Nano},
+	}
+}
+
+// LoadPhaseRules reads a json list of rules.
+func LoadPhaseRules(r io.Reader) ([]PhaseRule, error) {
+	rules := make([]PhaseRule, 0)
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, err
+	}
+	_, err := compilePhaseRules(rules)
+	return rules, err
+}
+
+type phaseRule struct {
+	PhaseRule
+	start *regexp.Regexp
+	end   *regexp.Regexp
+}
+
+func compilePhaseRules(rules []PhaseRule) ([]phaseRule, error) {
+	compiled := make([]phaseRule, 0, len(rules))
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("phase rule without name")
+		}
+		if r.TimeFormat == "" {
+			return nil, fmt.Errorf("phase %v: missing time format", r.Name)
+		}
+		start, err := regexp.Compile(r.Start)
+		if err != nil {
+			return nil, fmt.Errorf("phase %v: %v", r.Name, err)
+		}
+		end, err := regexp.Compile(r.End)
+		if err != nil {
+			return nil, fmt.Errorf("phase %v: %v", r.Name, err)
+		}
+		if start.NumSubexp() < 1 || end.NumSubexp() < 1 {
+			return nil, fmt.Errorf("phase %v: the timestamp group is missing", r.Name)
+		}
+		compiled = append(compiled, phaseRule{r, start, end})
+	}
+	return compiled, nil
+}
+
+// Phase is a phase found in the test output. End is zero when the phase
+// never finished.
+type Phase struct {
+	Name      string
+	Start     time.Time
+	StartLine string
+	End       time.Time
+	EndLine   string
+	// Depth is the number of phases still running when this one started.
+	Depth int
+}
+
+// Duration returns how long the phase took, zero if it never finished.
+func (p Phase) Duration() time.Duration {
+	if p.End.IsZero() {
+		return 0
+	}
+	return duration(p.Start, p.End)
+}
+
+// Phases holds the phases of a test.
+type Phases struct {
+	Phases []Phase
+	// Unmatched are the end lines without a running phase.
+	Unmatched []string
+	// Invalid are the start and end lines with a timestamp not in the time
+	// format of the rule.
+	Invalid []string
+}
+
+// Unfinished returns the phases that never ended.
+func (p Phases) Unfinished() []Phase {
+	u := make([]Phase, 0)
+	for _, ph := range p.Phases {
+		if ph.End.IsZero() {
+			u = append(u, ph)
+		}
+	}
+	return u
+}
+
+// phaseTracker finds the phases in the output of a test.
+type phaseTracker struct {
+	rules  []phaseRule
+	phases Phases
+	// running are the indexes of the unfinished phases
+	running []int
+}
+
+func newPhaseTracker(rules []phaseRule) *phaseTracker {
+	return &phaseTracker{rules: rules}
+}
+
+func (r phaseRule) time(m []string) (time.Time, error) {
+	return time.Parse(r.TimeFormat, m[1])
+}
+
+func (pt *phaseTracker) parse(line string) {
+	for _, r := range pt.rules {
+		if m := r.start.FindStringSubmatch(line); len(m) > 1 {
+			start, err := r.time(m)
+			if err != nil {
+				pt.phases.Invalid = append(pt.phases.Invalid, line)
+				return
+			}
+			pt.running = append(pt.running, len(pt.phases.Phases))
+			pt.phases.Phases = append(pt.phases.Phases, Phase{r.Name, start, line, time.Time{}, "", len(pt.running) - 1})
+			return
+		}
+	}
+	for _, r := range pt.rules {
+		if m := r.end.FindStringSubmatch(line); len(m) > 1 {
+			end, err := r.time(m)
+			if err != nil {
+				pt.phases.Invalid = append(pt.phases.Invalid, line)
+				return
+			}
+			// the innermost running phase of the rule ends
+			for i := len(pt.running) - 1; i >= 0; i-- {
+				p := &pt.phases.Phases[pt.running[i]]
+				if p.Name == r.Name {
+					p.End, p.EndLine = end, line
+					pt.running = append(pt.running[:i], pt.running[i+1:]...)
+					return
+				}
+			}
+			pt.phases.Unmatched = append(pt.phases.Unmatched, line)
+			return
+		}
+	}
+}
+
+// layoutParts reports whether a time.Parse layout has the year and the date.
+func layoutParts(layout string) (year, date bool) {
+	t := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	return t.Format(layout) != t.AddDate(1, 0, 0).Format(layout), t.Format(layout) != t.AddDate(0, 3, 3).Format(layout)
+}
+
+// anchor sets the missing year or date of a phase time to the closest one
+// around ref.
+func anchor(t, ref time.Time, year, date bool) time.Time {
+	switch {
+	case year || ref.IsZero():
+	case !date:
+		t = time.Date(ref.Year(), ref.Month(), ref.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		if ref.Sub(t) > 12*time.Hour {
+			t = t.AddDate(0, 0, 1)
+		} else if t.Sub(ref) > 12*time.Hour {
+			t = t.AddDate(0, 0, -1)
+		}
+	default:
+		t = withYear(t, ref.Year())
+		if ref.Sub(t) > halfYear {
+			t = withYear(t, ref.Year()+1)
+		} else if t.Sub(ref) > halfYear {
+			t = withYear(t, ref.Year()-1)
+		}
+	}
+	return t
+}
+
+// normalize gives the phase times without year or date those of the
+// normalized line before them, or of start for the first ones.
+func (p *Phases) normalize(lines []Line, start time.Time, rules []phaseRule) {
+	layouts := make(map[string]string)
+	for _, r := range rules {
+		layouts[r.Name] = r.TimeFormat
+	}
+	times := make(map[string][]*time.Time)
+	names := make(map[*time.Time]string)
+	for i := range p.Phases {
+		ph := &p.Phases[i]
+		times[ph.StartLine] = append(times[ph.StartLine], &ph.Start)
+		names[&ph.Start] = ph.Name
+		if !ph.End.IsZero() {
+			times[ph.EndLine] = append(times[ph.EndLine], &ph.End)
+			names[&ph.End] = ph.Name
+		}
+	}
+	last := start
+	for _, l := range lines {
+		if l.HasTime {
+			last = l.Time
+		}
+		ts := times[l.Line]
+		if len(ts) == 0 {
+			continue
+		}
+		t := ts[0]
+		times[l.Line] = ts[1:]
+		year, date := layoutParts(layouts[names[t]])
+		*t = anchor(*t, last, year, date)
+		last = *t
+	}
+}
+
+// done returns the phases found so far and starts over.
+func (pt *phaseTracker) done() Phases {
+	p := pt.phases
+	if p.Phases == nil {
+		p.Phases = make([]Phase, 0)
+	}
+	pt.phases, pt.running = Phases{}, nil
+	return p
+}
@@ -0,0 +1,130 @@
+package top
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhases(t *testing.T) {
+	rules, err := LoadPhaseRules(strings.NewReader(`[
+		{"name": "stage", "start": "^([0-9:]+) stage start", "end": "^([0-9:]+) stage end", "timeFormat": "15:04:05"},
+		{"name": "pull", "start": "^([0-9:]+) pulling", "end": "^([0-9:]+) pulled", "timeFormat": "15:04:05"}
+	]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	log := `------------------------------
+23:59:00 pulled stray end
+23:59:10 stage start
+23:59:20 pulling
+00:00:20 pulled
+00:01:10 stage end
+00:02:00 pulling
+• [SLOW TEST:180.000 seconds]
+`
+	r, err := Parse(strings.NewReader(log), Options{WindowSize: 5, Threshold: time.Minute, Phases: rules})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := r.Tests[0].Phases
+	expects := []struct {
+		name     string
+		depth    int
+		duration time.Duration
+		finished bool
+	}{
+		{"stage", 0, 2 * time.Minute, true},
+		{"pull", 1, time.Minute, true},
+		{"pull", 0, 0, false},
+	}
+	if len(p.Phases) != len(expects) {
+		t.Fatalf("Expected %v phases, got %v", len(expects), p.Phases)
+	}
+	for i, e := range expects {
+		ph := p.Phases[i]
+		if ph.Name != e.name || ph.Depth != e.depth || ph.Duration() != e.duration || ph.End.IsZero() == e.finished {
+			t.Errorf("Expected %v at depth %v taking %v, got %v at depth %v taking %v", e.name, e.depth, e.duration, ph.Name, ph.Depth, ph.Duration())
+		}
+	}
+	if len(p.Unmatched) != 1 || p.Unmatched[0] != "23:59:00 pulled stray end" {
+		t.Errorf("Expected the stray end unmatched, got %v", p.Unmatched)
+	}
+	if len(p.Unfinished()) != 1 {
+		t.Errorf("Expected one unfinished phase, got %v", p.Unfinished())
+	}
+}
+
+func TestPhaseDates(t *testing.T) {
+	rules, err := LoadPhaseRules(strings.NewReader(`[
+		{"name": "stage", "start": "^([0-9:]+) stage start", "end": "^([0-9:]+) stage end", "timeFormat": "15:04:05"},
+		{"name": "pull", "start": "^(\\w+ +\\d+ [0-9:]+) pulling", "end": "^(\\w+ +\\d+ [0-9:]+) pulled", "timeFormat": "Jan 2 15:04:05"}
+	]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	log := `------------------------------
+Dec 31 23:59:00.000: INFO: Running 'oc start-build'
+23:59:30 stage start
+Dec 31 23:59:40 pulling
+Jan 1 00:00:10 pulled
+00:00:30 stage end
+• [SLOW TEST:120.000 seconds]
+`
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	r, err := Parse(strings.NewReader(log), Options{WindowSize: 5, Threshold: time.Minute, Phases: rules, Start: start})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := r.Tests[0].Phases.Phases
+	expects := []struct {
+		start time.Time
+		end   time.Time
+	}{
+		{time.Date(2018, 12, 31, 23, 59, 30, 0, time.UTC), time.Date(2019, 1, 1, 0, 0, 30, 0, time.UTC)},
+		{time.Date(2018, 12, 31, 23, 59, 40, 0, time.UTC), time.Date(2019, 1, 1, 0, 0, 10, 0, time.UTC)},
+	}
+	if len(p) != len(expects) {
+		t.Fatalf("Expected %v phases, got %v", len(expects), p)
+	}
+	for i, e := range expects {
+		if !p[i].Start.Equal(e.start) || !p[i].End.Equal(e.end) {
+			t.Errorf("Expected %v from %v to %v, got %v to %v", p[i].Name, e.start, e.end, p[i].Start, p[i].End)
+		}
+	}
+}
+
+func TestPhaseInvalidTime(t *testing.T) {
+	rules, err := LoadPhaseRules(strings.NewReader(`[
+		{"name": "docker build", "start": "^(\\S+) Step 1/", "end": "^(\\S+) Successfully built", "timeFormat": "2006-01-02 15:04:05"}
+	]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	log := `------------------------------
+2018-04-03T11:49:00Z Step 1/3 : FROM centos
+2018-04-03T11:49:30Z Successfully built 0123456789ab
+• [SLOW TEST:30.000 seconds]
+`
+	r, err := Parse(strings.NewReader(log), Options{WindowSize: 5, Threshold: time.Minute, Phases: rules})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := r.Tests[0].Phases
+	if len(p.Phases) != 0 || len(p.Invalid) != 2 || p.Invalid[0] != "2018-04-03T11:49:00Z Step 1/3 : FROM centos" {
+		t.Errorf("Expected both lines with invalid times, got %+v", p)
+	}
+}
+
+func TestPhaseRuleErrors(t *testing.T) {
+	for _, rules := range []string{
+		`[{"name": "x", "start": "(", "end": "(a)", "timeFormat": "15:04:05"}]`,
+		`[{"name": "x", "start": "a", "end": "(a)", "timeFormat": "15:04:05"}]`,
+		`[{"name": "x", "start": "(a)", "end": "(a)"}]`,
+		`[{"start": "(a)", "end": "(a)", "timeFormat": "15:04:05"}]`,
+	} {
+		if _, err := LoadPhaseRules(strings.NewReader(rules)); err == nil {
+			t.Errorf("Expected error for %v", rules)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// slowSpecThreshold is the ginkgo default --slowSpecThreshold in seconds, used
+// to tell slow tests from reports without the SLOW TEST marker.
+const slowSpecThreshold = 5
+
+// Share is the number of tests and their time in seconds.
+type Share struct {
+	Tests   int     `json:"tests"`
+	Time    float64 `json:"time"`
+	Percent float64 `json:"percent"`
+}
+
+// Summary describes the whole suite.
+type Summary struct {
+	Tests  int            `json:"tests"`
+	Time   float64        `json:"time"`
+	Status map[string]int `json:"status"`
+	Slow   Share          `json:"slow"`
+	Fast   Share          `json:"fast"`
+	// Commands aggregates the oc invocations of all the tests.
+	Commands []CommandStats `json:"commands,omitempty"`
+	// Thresholds used to find the slow parts of the tests.
+	Thresholds Thresholds `json:"thresholds"`
+}
+
+// Summary counts the tests by status and the time taken by slow and fast
+// tests.
+func (r *Report) Summary() Summary {
+	s := Summary{Status: make(map[string]int), Thresholds: r.Thresholds}
+	for _, t := range r.Tests {
+		s.Tests++
+		s.Time += t.Time
+		s.Status[t.Status]++
+		share := &s.Fast
+		if t.Slow {
+			share = &s.Slow
+		}
+		share.Tests++
+		share.Time += t.Time
+	}
+	s.Commands = commandStats(r.Tests)
+	if s.Time > 0 {
+		s.Slow.Percent = 100 * s.Slow.Time / s.Time
+		s.Fast.Percent = 100 * s.Fast.Time / s.Time
+	}
+	return s
+}
+
+// ReadSummary reads the summary.json written by analyze.
+func ReadSummary(path string) (Summary, error) {
+	s := Summary{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%v: %v", path, err)
+	}
+	return s, nil
+}
+
+// Write writes the summary as text.
+func (s Summary) Write(w io.Writer) error {
+	statuses := make([]string, 0, len(s.Status))
+	for st := range s.Status {
+		statuses = append(statuses, st)
+	}
+	sort.Strings(statuses)
+	fmt.Fprintf(w, "tests: %v taking %v\n", s.Tests, Seconds(s.Time).Round(time.Second))
+	for _, st := range statuses {
+		fmt.Fprintf(w, "  %v: %v\n", st, s.Status[st])
+	}
+	fmt.Fprintf(w, "slow tests: %v taking %v (%.1f%%)\n", s.Slow.Tests, Seconds(s.Slow.Time).Round(time.Second), s.Slow.Percent)
+	if _, err := fmt.Fprintf(w, "fast tests: %v taking %v (%.1f%%)\n", s.Fast.Tests, Seconds(s.Fast.Time).Round(time.Second), s.Fast.Percent); err != nil {
+		return err
+	}
+	if err := s.Thresholds.Write(w); err != nil {
+		return err
+	}
+	return writeCommandStats(w, s.Commands)
+}
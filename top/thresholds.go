@@ -0,0 +1,190 @@
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Strategy is how the threshold of the slow parts of a test is chosen.
+type Strategy string
+
+// Threshold strategies.
+const (
+	// FixedThreshold uses Options.Threshold for all the tests.
+	FixedThreshold Strategy = "fixed"
+	// RelativeThreshold uses Options.Relative of the duration of each test,
+	// e.g. 0.2 for the parts taking more than 20% of the test.
+	RelativeThreshold Strategy = "relative"
+	// PercentileThreshold uses Options.Percentile of all the gaps between
+	// consecutive timestamped lines in the log, e.g. 99.
+	PercentileThreshold Strategy = "percentile"
+)
+
+// ThresholdOverride is the threshold in seconds of the tests with the names
+// matching the Test regular expression.
+type ThresholdOverride struct {
+	Test      string  `json:"test"`
+	Threshold float64 `json:"threshold"`
+}
+
+// LoadThresholdOverrides reads a json list of overrides.
+func LoadThresholdOverrides(r io.Reader) ([]ThresholdOverride, error) {
+	overrides := make([]ThresholdOverride, 0)
+	if err := json.NewDecoder(r).Decode(&overrides); err != nil {
+		return nil, err
+	}
+	_, err := compileOverrides(overrides)
+	return overrides, err
+}
+
+type thresholdOverride struct {
+	ThresholdOverride
+	test *regexp.Regexp
+}
+
+func compileOverrides(overrides []ThresholdOverride) ([]thresholdOverride, error) {
+	compiled := make([]thresholdOverride, 0, len(overrides))
+	for _, o := range overrides {
+		if !(o.Threshold >= 0) || o.Threshold > maxDuration.Seconds() {
+			return nil, fmt.Errorf("override %v: threshold must be in [0, %v], got %v", o.Test, maxDuration.Seconds(), o.Threshold)
+		}
+		test, err := regexp.Compile(o.Test)
+		if err != nil {
+			return nil, fmt.Errorf("override %v: %v", o.Test, err)
+		}
+		compiled = append(compiled, thresholdOverride{o, test})
+	}
+	return compiled, nil
+}
+
+// maxDuration is the threshold of the tests whose threshold overflows.
+const maxDuration = time.Duration(math.MaxInt64)
+
+// Seconds converts seconds to a duration, the maximal duration when it
+// overflows.
+func Seconds(s float64) time.Duration {
+	if d := s * float64(time.Second); d < float64(maxDuration) {
+		return time.Duration(d)
+	}
+	return maxDuration
+}
+
+// Thresholds are the strategy and the thresholds used for a report.
+type Thresholds struct {
+	Strategy Strategy `json:"strategy"`
+	// Threshold in seconds of the tests without an override, zero for
+	// RelativeThreshold.
+	Threshold  float64 `json:"threshold"`
+	Relative   float64 `json:"relative,omitempty"`
+	Percentile float64 `json:"percentile,omitempty"`
+	// Gaps is the number of gaps the percentile is taken from, Threshold is
+	// Options.Threshold when there are none.
+	Gaps      int                 `json:"gaps,omitempty"`
+	Overrides []ThresholdOverride `json:"overrides,omitempty"`
+
+	overrides []thresholdOverride
+}
+
+// gaps returns the times between consecutive timestamped lines of the tests.
+func gaps(tests []Test) []time.Duration {
+	d := make([]time.Duration, 0)
+	for _, t := range tests {
+		var prev time.Time
+		for _, l := range t.timed {
+			if !l.HasTime {
+				continue
+			}
+			// the jumps back are not gaps
+			if !prev.IsZero() && !l.Time.Before(prev) {
+				d = append(d, l.Time.Sub(prev))
+			}
+			prev = l.Time
+		}
+	}
+	return d
+}
+
+// linesTime returns the time between the first and the last timestamped line.
+func linesTime(lines []Line) time.Duration {
+	var first, last time.Time
+	for _, l := range lines {
+		if l.HasTime {
+			if first.IsZero() {
+				first = l.Time
+			}
+			last = l.Time
+		}
+	}
+	return duration(first, last)
+}
+
+func newThresholds(opts Options, tests []Test) Thresholds {
+	th := Thresholds{Strategy: opts.Strategy, Overrides: opts.Overrides}
+	th.overrides, _ = compileOverrides(opts.Overrides)
+	switch opts.Strategy {
+	case RelativeThreshold:
+		th.Relative = opts.Relative
+	case PercentileThreshold:
+		th.Percentile = opts.Percentile
+		d := gaps(tests)
+		th.Gaps = len(d)
+		th.Threshold = opts.Threshold.Seconds()
+		if len(d) > 0 {
+			sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+			// nearest rank
+			th.Threshold = d[int(math.Ceil(opts.Percentile/100*float64(len(d))))-1].Seconds()
+		}
+	default:
+		th.Strategy = FixedThreshold
+		th.Threshold = opts.Threshold.Seconds()
+	}
+	return th
+}
+
+// of returns the threshold of a test, from the first override matching its
+// name.
+func (th Thresholds) of(t *Test) time.Duration {
+	for _, o := range th.overrides {
+		if o.test.MatchString(t.Name()) {
+			return Seconds(o.Threshold)
+		}
+	}
+	if th.Strategy == RelativeThreshold {
+		d := Seconds(t.Time)
+		if d == 0 {
+			d = linesTime(t.timed)
+		}
+		return Seconds(th.Relative * d.Seconds())
+	}
+	return Seconds(th.Threshold)
+}
+
+// Write writes the thresholds as text, nothing when the report was not
+// processed.
+func (th Thresholds) Write(w io.Writer) error {
+	var err error
+	switch th.Strategy {
+	case "":
+		return nil
+	case RelativeThreshold:
+		_, err = fmt.Fprintf(w, "threshold: relative, %v%% of the test\n", 100*th.Relative)
+	case PercentileThreshold:
+		_, err = fmt.Fprintf(w, "threshold: percentile, p%v of %v gaps, %vs\n", th.Percentile, th.Gaps, th.Threshold)
+	default:
+		_, err = fmt.Fprintf(w, "threshold: %v, %vs\n", th.Strategy, th.Threshold)
+	}
+	if err != nil {
+		return err
+	}
+	for _, o := range th.Overrides {
+		if _, err := fmt.Fprintf(w, "  %v: %vs\n", o.Test, o.Threshold); err != nil {
+			return err
+		}
+	}
+	return nil
+}
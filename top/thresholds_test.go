@@ -0,0 +1,93 @@
+package top
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var thresholdLog = `------------------------------
+/go/src/github.com/openshift/origin/test/extended/builds/pipeline.go:437
+Apr  3 11:00:00.000: INFO: first
+Apr  3 11:00:10.000: INFO: second
+Apr  3 11:00:30.000: INFO: third
+Apr  3 11:02:00.000: INFO: fourth
+Apr  3 11:02:10.000: INFO: last
+• [SLOW TEST:130.000 seconds]
+------------------------------
+/go/src/github.com/openshift/origin/test/extended/builds/digest.go:65
+Apr  3 11:03:00.000: INFO: first
+Apr  3 11:03:05.000: INFO: second
+Apr  3 11:03:10.000: INFO: last
+•
+`
+
+func TestThresholds(t *testing.T) {
+	overrides, err := LoadThresholdOverrides(strings.NewReader(`[{"test": "digest", "threshold": 4}]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		name       string
+		opts       Options
+		thresholds []time.Duration
+		windows    []int
+		summary    string
+	}{
+		{"fixed", Options{WindowSize: 2, Detection: GapDetection, Threshold: time.Minute}, []time.Duration{time.Minute, time.Minute}, []int{1, 0}, "threshold: fixed, 60s\n"},
+		// 20% of 130s and of the 10s between the first and the last line
+		{"relative", Options{WindowSize: 2, Detection: GapDetection, Strategy: RelativeThreshold, Relative: 0.2}, []time.Duration{26 * time.Second, 2 * time.Second}, []int{1, 2}, "threshold: relative, 20% of the test\n"},
+		// gaps 5s, 5s, 10s, 10s, 20s, 90s
+		{"percentile", Options{WindowSize: 2, Detection: GapDetection, Strategy: PercentileThreshold, Percentile: 50}, []time.Duration{10 * time.Second, 10 * time.Second}, []int{2, 0}, "threshold: percentile, p50 of 6 gaps, 10s\n"},
+		{"override", Options{WindowSize: 2, Detection: GapDetection, Threshold: time.Minute, Overrides: overrides}, []time.Duration{time.Minute, 4 * time.Second}, []int{1, 2}, "threshold: fixed, 60s\n  digest: 4s\n"},
+	}
+	for _, test := range tests {
+		r, err := Parse(strings.NewReader(thresholdLog), test.opts)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		for i, e := range test.thresholds {
+			tc := r.Tests[i]
+			if tc.Threshold != e || tc.Blocks.Threshold != e.Seconds() || len(tc.Windows) != test.windows[i] {
+				t.Errorf("%v: Expected test %v threshold %v with %v windows, got %v with %v", test.name, i, e, test.windows[i], tc.Threshold, len(tc.Windows))
+			}
+		}
+		out := &bytes.Buffer{}
+		if err := r.Summary().Thresholds.Write(out); err != nil {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		if out.String() != test.summary {
+			t.Errorf("%v: Expected %q, got %q", test.name, test.summary, out.String())
+		}
+	}
+}
+
+func TestThresholdErrors(t *testing.T) {
+	for _, opts := range []Options{
+		{WindowSize: 5, Strategy: "other"},
+		{WindowSize: 5, Strategy: RelativeThreshold},
+		{WindowSize: 5, Strategy: PercentileThreshold, Percentile: 101},
+		{WindowSize: 5, Overrides: []ThresholdOverride{{"(", 1}}},
+		{WindowSize: 5, Overrides: []ThresholdOverride{{"a", -1}}},
+		{WindowSize: 5, Overrides: []ThresholdOverride{{"a", math.NaN()}}},
+		{WindowSize: 5, Overrides: []ThresholdOverride{{"a", 1e300}}},
+		{WindowSize: 5, Strategy: RelativeThreshold, Relative: math.NaN()},
+		{WindowSize: 5, Strategy: RelativeThreshold, Relative: math.Inf(1)},
+		{WindowSize: 5, Strategy: PercentileThreshold, Percentile: math.NaN()},
+		{WindowSize: 5, Strategy: PercentileThreshold, Percentile: math.Inf(1)},
+	} {
+		if _, err := Parse(strings.NewReader(""), opts); err == nil {
+			t.Errorf("Expected error for %+v", opts)
+		}
+	}
+	// the threshold overflowing a duration is the longest one
+	r, err := Parse(strings.NewReader(thresholdLog), Options{WindowSize: 2, Strategy: RelativeThreshold, Relative: 1e12})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if th := r.Tests[0].Threshold; th != maxDuration || len(r.Tests[0].Windows) != 0 {
+		t.Errorf("Expected %v threshold without windows, got %v with %v", maxDuration, th, r.Tests[0].Windows)
+	}
+}
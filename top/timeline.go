@@ -0,0 +1,104 @@
+package top
+
+import (
+	"regexp"
+	"time"
+)
+
+var fullTimeRegexp = regexp.MustCompile(`[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?Z`)
+
+// halfYear is how far back a timestamp without year may go before it is
+// considered to be in the next year.
+const halfYear = 183 * 24 * time.Hour
+
+// Jump is a timestamp that is earlier than the one before it.
+type Jump struct {
+	From time.Time
+	To   time.Time
+	Line string
+}
+
+// Timeline assigns years to the timestamps without one, e.g. `Apr  3
+// 11:48:35.747`, so the times keep increasing across Dec 31 → Jan 1.
+type Timeline struct {
+	ref   time.Time
+	last  time.Time
+	Jumps []Jump
+}
+
+// NewTimeline returns a timeline starting around ref, usually the start of the
+// build. The year stays 0 when ref is zero.
+func NewTimeline(ref time.Time) *Timeline {
+	return &Timeline{ref: ref}
+}
+
+// fullTime returns the first full timestamp in the line.
+func fullTime(line string) (time.Time, bool) {
+	m := fullTimeRegexp.FindString(line)
+	if m == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, m)
+	return t, err == nil
+}
+
+func withYear(t time.Time, year int) time.Time {
+	return time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// Normalize sets the year of t, parsed from a timestamp without year, and
+// records a Jump when the result is earlier than the previous timestamp.
+func (tl *Timeline) Normalize(t time.Time, line string) time.Time {
+	if t.Year() == 0 {
+		switch {
+		case !tl.last.IsZero():
+			t = withYear(t, tl.last.Year())
+			if tl.last.Sub(t) > halfYear {
+				t = withYear(t, tl.last.Year()+1)
+			}
+		case !tl.ref.IsZero():
+			// the first timestamp may be a few hours before or after the
+			// reference, e.g. the log starts Dec 31 and the build on Jan 1
+			best := withYear(t, tl.ref.Year())
+			for _, y := range []int{tl.ref.Year() - 1, tl.ref.Year() + 1} {
+				if c := withYear(t, y); abs(c.Sub(tl.ref)) < abs(best.Sub(tl.ref)) {
+					best = c
+				}
+			}
+			t = best
+		}
+	}
+	if !tl.last.IsZero() && t.Before(tl.last) {
+		tl.Jumps = append(tl.Jumps, Jump{tl.last, t, line})
+	}
+	tl.last = t
+	return t
+}
+
+// lines returns the lines with the timestamps normalized, in order.
+func (tl *Timeline) lines(lines []string) []Line {
+	timed := make([]Line, len(lines))
+	for i, l := range lines {
+		timed[i].Line = l
+		if t, ok := lineTime(l); ok {
+			timed[i].Time, timed[i].HasTime = tl.Normalize(t, l), true
+		}
+	}
+	return timed
+}
+
+// duration returns the time between two normalized timestamps, zero when the
+// time goes backwards, the Timeline records those as jumps.
+func duration(from, to time.Time) time.Duration {
+	if d := to.Sub(from); d > 0 {
+		return d
+	}
+	return 0
+}
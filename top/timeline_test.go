@@ -0,0 +1,65 @@
+package top
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimelineRollover(t *testing.T) {
+	lines := []string{
+		"Dec 31 23:59:58.500: INFO: Running 'oc new-app'",
+		"Jan  1 00:00:01.000: INFO: Running 'oc start-build'",
+		"Jan  1 00:03:01.000: INFO: Running 'oc delete'",
+	}
+	ref := time.Date(2019, 1, 1, 0, 10, 0, 0, time.UTC)
+	windows, blocks, err := Process(lines, Options{WindowSize: 2, Threshold: 100 * time.Second, Start: ref})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(windows) != 1 || windows[0].Time() != 180*time.Second {
+		t.Errorf("Expected one 180s window, got %v", windows)
+	}
+	first := windows[0].TimedWindow[0].Time
+	if !first.Equal(time.Date(2019, 1, 1, 0, 0, 1, 0, time.UTC)) {
+		t.Errorf("Expected window to start 2019-01-01T00:00:01Z, got %v", first)
+	}
+	if blocks.Blocks[0].End != 2.5 {
+		t.Errorf("Expected first block to end at 2.5s, got %v", blocks.Blocks[0].End)
+	}
+}
+
+func TestTimelineJumps(t *testing.T) {
+	tl := NewTimeline(time.Time{})
+	for _, l := range []string{"Apr  3 11:48:35", "Apr  3 11:48:40", "Apr  3 11:48:30", "Apr  3 11:48:31"} {
+		ts, _ := time.Parse(`Jan 2 15:04:05`, l)
+		tl.Normalize(ts, l)
+	}
+	if len(tl.Jumps) != 1 || tl.Jumps[0].Line != "Apr  3 11:48:30" || tl.Jumps[0].From.Sub(tl.Jumps[0].To) != 10*time.Second {
+		t.Errorf("Expected one 10s jump to Apr  3 11:48:30, got %v", tl.Jumps)
+	}
+}
+
+func TestParseFullTimestamp(t *testing.T) {
+	log := `------------------------------
+Dec 31 23:59:00.000: INFO: Running 'oc start-build'
+2018-12-31T23:59:50.000Z Step 1/3 : FROM centos
+2019-01-01T00:00:20.000Z Successfully built 0123456789ab
+Jan  1 00:01:00.000: INFO: Done
+• [SLOW TEST:120.000 seconds]
+`
+	r, err := Parse(strings.NewReader(log), DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tst := r.Tests[0]
+	if d := tst.Phases.Phases[0].Duration(); d != 30*time.Second {
+		t.Errorf("Expected 30s docker build across midnight, got %v", d)
+	}
+	if b := tst.Blocks.Blocks[0]; b.End != 120 {
+		t.Errorf("Expected block to end at 120s, got %v", b.End)
+	}
+	if len(r.Jumps) != 0 {
+		t.Errorf("Expected no jumps, got %v", r.Jumps)
+	}
+}
@@ -0,0 +1,355 @@
+// Package top parses origin extended test logs and identifies slow parts of
+// the individual tests.
+package top
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var slowTestRegexp = regexp.MustCompile(`^• \[SLOW TEST:(.*) seconds\]$`)
+var specTimeRegexp = regexp.MustCompile(`\[([0-9.]+) seconds\]$`)
+var fileNameRegexp = regexp.MustCompile(`.*(/test/extended/.*\.go.*)`)
+var ignoreLines = []string{`INFO: Running AfterSuite actions on all node`}
+
+// Options control how the test output is split into windows and blocks.
+type Options struct {
+	// WindowSize is the number of timestamped lines in a sliding window.
+	WindowSize int
+	// Threshold to identify slow windows with FixedThreshold, the fallback
+	// of PercentileThreshold for logs without timestamps.
+	Threshold time.Duration
+	// Strategy of the thresholds of the tests, FixedThreshold when empty.
+	Strategy Strategy
+	// Relative is the share of the test duration for RelativeThreshold.
+	Relative float64
+	// Percentile of the gaps between the lines for PercentileThreshold.
+	Percentile float64
+	// Overrides are the thresholds of the matching tests, the first match
+	// wins over the strategy.
+	Overrides []ThresholdOverride
+	// Start of the build, used to find the year of the timestamps. The first
+	// full timestamp in the log is used when zero.
+	Start time.Time
+	// Phases to find in the test output, DefaultPhaseRules when nil.
+	Phases []PhaseRule
+	// Detection of the slow parts, WindowDetection when empty.
+	Detection Detection
+}
+
+// Detection is how the slow parts of a test are found.
+type Detection string
+
+// Detections.
+const (
+	// WindowDetection finds the sliding windows of WindowSize timestamped
+	// lines taking more than Threshold.
+	WindowDetection Detection = "window"
+	// GapDetection finds the gaps longer than Threshold between consecutive
+	// timestamped lines, the windows are the lines before and after the gap.
+	GapDetection Detection = "gap"
+)
+
+func (o Options) phaseRules() ([]phaseRule, error) {
+	if o.Phases == nil {
+		return compilePhaseRules(DefaultPhaseRules())
+	}
+	return compilePhaseRules(o.Phases)
+}
+
+// DefaultOptions returns the options used by the command line tool.
+func DefaultOptions() Options {
+	return Options{WindowSize: 5, Threshold: 120 * time.Second}
+}
+
+func (o Options) validate() error {
+	if o.WindowSize < 1 {
+		return fmt.Errorf("window size must be positive, got %v", o.WindowSize)
+	}
+	if o.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative, got %v", o.Threshold)
+	}
+	if o.Detection != "" && o.Detection != WindowDetection && o.Detection != GapDetection {
+		return fmt.Errorf("unknown detection %q, %v or %v", o.Detection, WindowDetection, GapDetection)
+	}
+	switch o.Strategy {
+	case "", FixedThreshold:
+	case RelativeThreshold:
+		if !(o.Relative > 0) || math.IsInf(o.Relative, 0) {
+			return fmt.Errorf("relative threshold must be positive, got %v", o.Relative)
+		}
+	case PercentileThreshold:
+		if !(o.Percentile > 0 && o.Percentile <= 100) {
+			return fmt.Errorf("percentile must be in (0, 100], got %v", o.Percentile)
+		}
+	default:
+		return fmt.Errorf("unknown threshold strategy %q, %v, %v or %v", o.Strategy, FixedThreshold, RelativeThreshold, PercentileThreshold)
+	}
+	if _, err := compileOverrides(o.Overrides); err != nil {
+		return err
+	}
+	_, err := o.phaseRules()
+	return err
+}
+
+// Report holds all the tests found in a log.
+type Report struct {
+	Tests []Test
+	// Jumps are the timestamps going backwards in the log.
+	Jumps []Jump
+	// Thresholds used to find the slow parts of the tests.
+	Thresholds Thresholds
+}
+
+// Test statuses.
+const (
+	Passed  = "passed"
+	Failed  = "failed"
+	Skipped = "skipped"
+	Pending = "pending"
+)
+
+// Test is a single test with its output and the identified slow parts.
+type Test struct {
+	// Spec is the name of the test from structured reports, e.g. JUnit.
+	Spec   string
+	Status string
+	// Slow is set for the tests ginkgo reports as SLOW TEST.
+	Slow bool
+	// Node is the parallel ginkgo node running the test, 0 when unknown.
+	Node int
+	// Time is the test duration in seconds, the time between the first and
+	// the last timestamp when ginkgo does not report it.
+	Time float64
+	// Start is the first timestamp of the test output, or the start from
+	// the report when the output has no timestamps.
+	Start time.Time
+	// Threshold used to find the slow parts of the test.
+	Threshold time.Duration
+	Phases    Phases
+	// Commands are the oc invocations in the output.
+	Commands []Command
+	// Waits are the waits for builds and other objects in the output.
+	Waits   []Wait
+	Lines   []string
+	Windows []Window
+	Blocks  Blocks
+
+	timeFromLines bool
+	// timed are the Lines with the timestamps normalized by the timeline of
+	// the log.
+	timed []Line
+}
+
+// Name returns the Spec when known, otherwise the test file and line number,
+// or "unknown".
+func (t Test) Name() string {
+	if t.Spec != "" {
+		return t.Spec
+	}
+	if l := t.Location(); l != "" {
+		return l
+	}
+	return "unknown"
+}
+
+// Location returns the test file and line number found in the output, e.g.
+// /test/extended/builds/pipeline.go:437, empty when there is none.
+func (t Test) Location() string {
+	for _, l := range t.Lines {
+		if m := fileNameRegexp.FindStringSubmatch(l); len(m) > 1 {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// Starts returns the start of every test and the earliest one. The tests
+// without a start follow the previous test of their node.
+func (r *Report) Starts() ([]time.Time, time.Time) {
+	s := make([]time.Time, len(r.Tests))
+	next := make(map[int]time.Time)
+	var first time.Time
+	for _, t := range r.Tests {
+		if !t.Start.IsZero() && (first.IsZero() || t.Start.Before(first)) {
+			first = t.Start
+		}
+	}
+	for i, t := range r.Tests {
+		s[i] = t.Start
+		if s[i].IsZero() {
+			s[i] = next[t.Node]
+			if s[i].IsZero() {
+				s[i] = first
+			}
+		}
+		if end := s[i].Add(Seconds(t.Time)); end.After(next[t.Node]) {
+			next[t.Node] = end
+		}
+	}
+	return s, first
+}
+
+// Parse reads a Jenkins console log and returns the tests found in it.
+func Parse(r io.Reader, opts Options) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	report, start, err := parse(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	report.process(opts, start)
+	return report, nil
+}
+
+// parse reads the tests of a log without processing them and returns the
+// start of the log.
+func parse(r io.Reader, opts Options) (*Report, time.Time, error) {
+	rules, _ := opts.phaseRules()
+	report := &Report{Tests: make([]Test, 0)}
+	scanner := bufio.NewScanner(r)
+	buffer := make([]string, 0)
+	phases := newPhaseTracker(rules)
+	start := opts.Start
+	for scanner.Scan() {
+		line := scanner.Text()
+		if ignore(line) {
+			continue
+		}
+		if start.IsZero() {
+			start, _ = fullTime(line)
+		}
+		if t, ok, err := specEnd(line); err != nil {
+			return nil, time.Time{}, err
+		} else if ok {
+			//end
+			t.Phases, t.Lines = phases.done(), buffer
+			report.Tests = append(report.Tests, t)
+			buffer = make([]string, 0)
+		} else if strings.HasPrefix(line, "------------------------------") {
+			//start
+			buffer = make([]string, 0)
+			phases.done()
+		} else {
+			//middle
+			phases.parse(line)
+		}
+		buffer = append(buffer, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, time.Time{}, err
+	}
+	return report, start, nil
+}
+
+// specEnd parses the line ginkgo prints at the end of a spec, e.g.
+// `• [SLOW TEST:552.000 seconds]`, `• Failure [7.123 seconds]`,
+// `S [SKIPPING] [0.100 seconds]` or just `•` for a passed spec.
+func specEnd(line string) (Test, bool, error) {
+	t := Test{}
+	switch {
+	case line == "•":
+		t.Status, t.timeFromLines = Passed, true
+		return t, true, nil
+	case strings.HasPrefix(line, "• [SLOW TEST:"):
+		m := slowTestRegexp.FindStringSubmatch(line)
+		if len(m) < 2 {
+			return t, false, fmt.Errorf("malformed slow test line %q", line)
+		}
+		time, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return t, false, err
+		}
+		t.Status, t.Slow, t.Time = Passed, true, time
+		return t, true, nil
+	case strings.HasPrefix(line, "• Failure"), strings.HasPrefix(line, "• Panic"):
+		t.Status = Failed
+	case strings.HasPrefix(line, "• [MEASUREMENT]"):
+		t.Status = Passed
+	case strings.HasPrefix(line, "S [SKIPPING]"):
+		t.Status = Skipped
+	case strings.HasPrefix(line, "P [PENDING]"):
+		t.Status = Pending
+	default:
+		return t, false, nil
+	}
+	if m := specTimeRegexp.FindStringSubmatch(line); len(m) > 1 {
+		time, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return t, false, err
+		}
+		t.Time = time
+	} else {
+		t.timeFromLines = true
+	}
+	return t, true, nil
+}
+
+// process finds the windows and blocks of all the tests. The tests share the
+// timeline so the years roll over between them too.
+func (r *Report) process(opts Options, start time.Time) {
+	r.normalize(start)
+	r.Thresholds = newThresholds(opts, r.Tests)
+	r.processTests(opts)
+}
+
+// normalize puts the lines of all the tests through one timeline.
+func (r *Report) normalize(start time.Time) {
+	timeline := NewTimeline(start)
+	for i := range r.Tests {
+		r.Tests[i].timed = timeline.lines(r.Tests[i].Lines)
+	}
+	r.Jumps = timeline.Jumps
+}
+
+// processTests processes the normalized tests with the thresholds of the
+// report.
+func (r *Report) processTests(opts Options) {
+	rules, _ := opts.phaseRules()
+	for i := range r.Tests {
+		t := &r.Tests[i]
+		t.Threshold = r.Thresholds.of(t)
+		opts.Threshold = t.Threshold
+		t.Windows, t.Blocks = process(t.timed, opts)
+		if !t.Blocks.offset.IsZero() {
+			t.Start = t.Blocks.offset
+		}
+		if t.timeFromLines && len(t.Blocks.Blocks) > 0 {
+			t.Time = t.Blocks.Blocks[len(t.Blocks.Blocks)-1].End
+		}
+		t.Phases.normalize(t.timed, t.Start, rules)
+		t.fillStats()
+		t.Commands = commands(t.timed)
+		t.Blocks.Commands = t.Commands
+		t.Waits = waits(t.timed)
+		t.Blocks.Waits = t.Waits
+	}
+}
+
+// fillStats sets the name, duration, status and wall-clock times of the
+// stats.json entry.
+func (t *Test) fillStats() {
+	t.Blocks.Name, t.Blocks.Time, t.Blocks.Status = t.Name(), t.Time, t.Status
+	t.Blocks.Threshold = t.Threshold.Seconds()
+	if !t.Start.IsZero() {
+		t.Blocks.Start = t.Start
+		t.Blocks.End = t.Start.Add(Seconds(t.Time))
+	}
+}
+
+func ignore(line string) bool {
+	for _, l := range ignoreLines {
+		if strings.Contains(line, l) {
+			return true
+		}
+	}
+	return false
+}
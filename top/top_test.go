@@ -0,0 +1,240 @@
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+var log = `------------------------------
+[builds] test
+/go/src/github.com/openshift/origin/test/extended/builds/pipeline.go:437
+Apr  3 11:48:35.747: INFO: Running 'oc new-app'
+Apr  3 11:48:39.007: INFO: Running 'oc start-build'
+Apr  3 11:48:40.287: INFO: Waiting for openshift-jee-sample-1 to complete
+Apr  3 11:57:41.804: INFO: Done waiting for openshift-jee-sample-1
+Apr  3 11:57:41.906: INFO: Running 'oc delete'
+Apr  3 11:57:42.545: INFO: Running 'oc delete'
+2018-04-03T11:49:00.123Z Step 1/3 : FROM centos
+2018-04-03T11:49:30.623Z Successfully built 0123456789ab
+
+• [SLOW TEST:552.000 seconds]
+------------------------------
+[builds] other test
+/go/src/github.com/openshift/origin/test/extended/builds/digest.go:65
+Apr  3 11:58:00.000: INFO: Running 'oc create'
+Apr  3 11:58:01.000: INFO: Running 'oc delete'
+
+• [SLOW TEST:6.500 seconds]
+`
+
+func TestParse(t *testing.T) {
+	r, err := Parse(strings.NewReader(log), DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.Tests) != 2 {
+		t.Fatalf("Expected 2 tests, got %v", len(r.Tests))
+	}
+	first := r.Tests[0]
+	if first.Time != 552 {
+		t.Errorf("Expected time 552, got %v", first.Time)
+	}
+	if first.Name() != "/test/extended/builds/pipeline.go:437" {
+		t.Errorf("Expected name /test/extended/builds/pipeline.go:437, got %v", first.Name())
+	}
+	if len(first.Phases.Phases) != 1 || first.Phases.Phases[0].Duration() != 30500*time.Millisecond {
+		t.Errorf("Expected one 30.5s docker build, got %v", first.Phases.Phases)
+	}
+	if len(first.Windows) != 1 || first.Windows[0].Time() != 546057*time.Millisecond {
+		t.Errorf("Expected one 546.057s window, got %v", first.Windows)
+	}
+	slow := first.Blocks.Blocks[1]
+	if slow.Start != 0 || slow.End != 546.057 {
+		t.Errorf("Expected slow block 0-546.057, got %v-%v", slow.Start, slow.End)
+	}
+	types := make([]string, 0)
+	for _, b := range first.Blocks.Blocks {
+		types = append(types, b.BlockType)
+	}
+	if strings.Join(types, ",") != "fast,slow,fast" {
+		t.Errorf("Expected fast,slow,fast blocks, got %v", types)
+	}
+	if r.Tests[1].Name() != "/test/extended/builds/digest.go:65" || len(r.Tests[1].Windows) != 0 {
+		t.Errorf("Unexpected second test: %v", r.Tests[1])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse(strings.NewReader(log), Options{}); err == nil {
+		t.Errorf("Expected error for zero window size")
+	}
+	if _, err := Parse(strings.NewReader("• [SLOW TEST:abc seconds]\n"), DefaultOptions()); err == nil {
+		t.Errorf("Expected error for malformed duration")
+	}
+}
+
+func TestParseAllSpecs(t *testing.T) {
+	log := `------------------------------
+/go/src/github.com/openshift/origin/test/extended/builds/fast.go:10
+Apr  3 11:58:00.000: INFO: Running 'oc create'
+Apr  3 11:58:02.500: INFO: Running 'oc delete'
+•
+------------------------------
+/go/src/github.com/openshift/origin/test/extended/builds/failed.go:20
+Apr  3 11:58:03.000: INFO: Running 'oc create'
+• Failure [130.250 seconds]
+/go/src/github.com/openshift/origin/test/extended/builds/failed.go:20
+------------------------------
+S [SKIPPING] in Spec Setup (BeforeEach) [0.100 seconds]
+------------------------------
+P [PENDING]
+------------------------------
+/go/src/github.com/openshift/origin/test/extended/builds/slow.go:30
+• [SLOW TEST:70.000 seconds]
+`
+	r, err := Parse(strings.NewReader(log), DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expects := []struct {
+		name   string
+		status string
+		time   float64
+		slow   bool
+	}{
+		{"/test/extended/builds/fast.go:10", Passed, 2.5, false},
+		{"/test/extended/builds/failed.go:20", Failed, 130.25, false},
+		{"unknown", Skipped, 0.1, false},
+		{"unknown", Pending, 0, false},
+		{"/test/extended/builds/slow.go:30", Passed, 70, true},
+	}
+	if len(r.Tests) != len(expects) {
+		t.Fatalf("Expected %v tests, got %v", len(expects), len(r.Tests))
+	}
+	for i, e := range expects {
+		tc := r.Tests[i]
+		if tc.Name() != e.name || tc.Status != e.status || tc.Time != e.time || tc.Slow != e.slow {
+			t.Errorf("Expected %v %v %v %v, got %v %v %v %v", e.name, e.status, e.time, e.slow, tc.Name(), tc.Status, tc.Time, tc.Slow)
+		}
+	}
+	s := r.Summary()
+	if s.Tests != 5 || s.Status[Passed] != 2 || s.Status[Failed] != 1 || s.Slow.Tests != 1 || s.Slow.Time != 70 || s.Fast.Time != 132.85 {
+		t.Errorf("Unexpected summary %+v", s)
+	}
+}
+
+func TestGapDetection(t *testing.T) {
+	lines := []string{
+		"Apr  3 11:00:00.000: INFO: first",
+		"Apr  3 11:00:10.000: INFO: before",
+		"no timestamp",
+		"Apr  3 11:03:10.000: INFO: after",
+		"Apr  3 11:03:20.000: INFO: before short",
+		"Apr  3 11:05:30.000: INFO: after short",
+		"Apr  3 11:05:40.000: INFO: last",
+	}
+	opts := DefaultOptions()
+	opts.Detection = GapDetection
+	windows, blocks, err := Process(lines, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("Expected 2 gaps, got %v", len(windows))
+	}
+	expects := []struct {
+		before, after string
+		time          time.Duration
+	}{
+		{lines[1], lines[3], 180 * time.Second},
+		{lines[4], lines[5], 130 * time.Second},
+	}
+	for i, e := range expects {
+		w := windows[i]
+		if !w.Gap || len(w.TimedWindow) != 2 || w.TimedWindow[0].Line != e.before || w.TimedWindow[1].Line != e.after || w.Time() != e.time {
+			t.Errorf("Expected gap %v - %v of %v, got %+v", e.before, e.after, e.time, w)
+		}
+	}
+	types := make([]string, 0)
+	for _, b := range blocks.Blocks {
+		types = append(types, fmt.Sprintf("%v %v-%v", b.BlockType, b.Start, b.End))
+	}
+	if strings.Join(types, ",") != "fast 0-10,slow 10-190,fast 190-200,slow 200-330,fast 330-340" {
+		t.Errorf("Unexpected blocks %v", types)
+	}
+	// the sliding window skips past the first stall and misses the second
+	windows, _, _ = Process(lines, DefaultOptions())
+	if len(windows) != 1 || len(windows[0].TimedWindow) != 3 || windows[0].Gap {
+		t.Errorf("Expected one 3 line window, got %+v", windows)
+	}
+	if _, err := Parse(strings.NewReader(""), Options{WindowSize: 5, Detection: "other"}); err == nil {
+		t.Errorf("Expected error for unknown detection")
+	}
+}
+
+func TestProcess(t *testing.T) {
+	lines := []string{
+		"Apr  3 11:00:00.000: INFO: first",
+		"Apr  3 11:00:10.000: INFO: second",
+		"Apr  3 11:01:40.000: INFO: last",
+	}
+	if _, _, err := Process(lines, Options{}); err == nil {
+		t.Errorf("Expected error for zero options")
+	}
+	// 20% of the 100s between the first and the last line
+	windows, blocks, err := Process(lines, Options{WindowSize: 2, Detection: GapDetection, Strategy: RelativeThreshold, Relative: 0.2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(windows) != 1 || windows[0].Time() != 90*time.Second || blocks.Threshold != 20 {
+		t.Errorf("Expected one 90s gap over the 20s threshold, got %v over %v", windows, blocks.Threshold)
+	}
+}
+
+func TestStarts(t *testing.T) {
+	start := time.Date(2018, 4, 3, 12, 0, 0, 0, time.UTC)
+	r := &Report{Tests: []Test{
+		{Node: 1, Time: 10},
+		{Node: 2, Time: 5, Start: start},
+		{Node: 1, Time: 1},
+		{Node: 2, Time: 1},
+	}}
+	s, first := r.Starts()
+	if !first.Equal(start) {
+		t.Errorf("Expected first start %v, got %v", start, first)
+	}
+	expects := []time.Time{start, start, start.Add(10 * time.Second), start.Add(5 * time.Second)}
+	for i, e := range expects {
+		if !s[i].Equal(e) {
+			t.Errorf("Expected test %v at %v, got %v", i, e, s[i])
+		}
+	}
+}
+
+func TestWallClockStats(t *testing.T) {
+	log := `------------------------------
+/go/src/github.com/openshift/origin/test/extended/builds/a.go:1
+2018-04-03T12:00:00Z start
+Apr  3 12:00:01.000: INFO: first
+Apr  3 12:00:04.000: INFO: last
+•
+`
+	r, err := Parse(strings.NewReader(log), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := r.Tests[0].Blocks
+	if !b.Start.Equal(time.Date(2018, 4, 3, 12, 0, 1, 0, time.UTC)) || !b.End.Equal(time.Date(2018, 4, 3, 12, 0, 4, 0, time.UTC)) {
+		t.Errorf("Expected the test from 12:00:01 to 12:00:04, got %v %v", b.Start, b.End)
+	}
+	data, err := json.Marshal(Blocks{Name: "untimed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"start"`) {
+		t.Errorf("Expected no wall-clock times without timestamps, got %s", data)
+	}
+}
@@ -0,0 +1,78 @@
+package top
+
+import (
+	"regexp"
+	"time"
+)
+
+var waitStartRegexp = regexp.MustCompile(`INFO: Waiting for ([^\s:]+) to complete`)
+var waitEndRegexp = regexp.MustCompile(`INFO: Done waiting for ([^\s:]+)`)
+
+// Wait outcomes, the first set flag of the util.BuildResult dump.
+const (
+	WaitSuccess    = "success"
+	WaitFailure    = "failure"
+	WaitCancelled  = "cancelled"
+	WaitTimeout    = "timeout"
+	WaitUnfinished = "unfinished"
+)
+
+var waitOutcomes = []struct {
+	flag    *regexp.Regexp
+	outcome string
+}{
+	{regexp.MustCompile(`\bBuildTimeout:true\b`), WaitTimeout},
+	{regexp.MustCompile(`\bBuildCancelled:true\b`), WaitCancelled},
+	{regexp.MustCompile(`\bBuildFailure:true\b`), WaitFailure},
+	{regexp.MustCompile(`\bBuildSuccess:true\b`), WaitSuccess},
+}
+
+// Wait is the time a test waited for an object, e.g. a build. Start is in
+// seconds since the first timestamped line of the test. Outcome is empty when
+// the end line has no build result.
+type Wait struct {
+	Object  string  `json:"object"`
+	Start   float64 `json:"start"`
+	Time    float64 `json:"time"`
+	Outcome string  `json:"outcome,omitempty"`
+}
+
+func waitOutcome(line string) string {
+	for _, o := range waitOutcomes {
+		if o.flag.MatchString(line) {
+			return o.outcome
+		}
+	}
+	return ""
+}
+
+// waits pairs the `Waiting for X to complete` and `Done waiting for X` lines
+// of a test by the object name.
+func waits(lines []Line) []Wait {
+	ws := make([]Wait, 0)
+	running := make(map[string]int)
+	started := make(map[string]time.Time)
+	var first time.Time
+	for _, l := range lines {
+		if !l.HasTime {
+			continue
+		}
+		t := l.Time
+		if first.IsZero() {
+			first = t
+		}
+		if m := waitStartRegexp.FindStringSubmatch(l.Line); len(m) > 1 {
+			running[m[1]], started[m[1]] = len(ws), t
+			ws = append(ws, Wait{Object: m[1], Start: duration(first, t).Seconds(), Outcome: WaitUnfinished})
+		} else if m := waitEndRegexp.FindStringSubmatch(l.Line); len(m) > 1 {
+			i, ok := running[m[1]]
+			if !ok {
+				continue
+			}
+			ws[i].Time = duration(started[m[1]], t).Seconds()
+			ws[i].Outcome = waitOutcome(l.Line)
+			delete(running, m[1])
+		}
+	}
+	return ws
+}
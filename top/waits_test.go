@@ -0,0 +1,37 @@
+package top
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaits(t *testing.T) {
+	lines := []string{
+		`Apr  3 11:48:40.287: INFO: Waiting for sample-1 to complete`,
+		`Apr  3 11:48:41.000: INFO: Waiting for sample-2 to complete`,
+		`Apr  3 11:49:00.000: INFO: Done waiting for unknown-1: util.BuildResult{BuildSuccess:true}`,
+		`Apr  3 11:50:41.000: INFO: Done waiting for sample-2: util.BuildResult{BuildPath:"build/sample-2", BuildAttempt:true, BuildSuccess:false, BuildFailure:false, BuildCancelled:false, BuildTimeout:true}`,
+		`Apr  3 11:57:41.804: INFO: Done waiting for sample-1: util.BuildResult{BuildPath:"build/sample-1", BuildAttempt:true, BuildSuccess:true, BuildFailure:false, BuildCancelled:false, BuildTimeout:false}`,
+		`Apr  3 11:58:00.000: INFO: Waiting for sample-3 to complete`,
+	}
+	expects := []Wait{
+		{Object: "sample-1", Start: 0, Time: 541.517, Outcome: WaitSuccess},
+		{Object: "sample-2", Start: 0.713, Time: 120, Outcome: WaitTimeout},
+		{Object: "sample-3", Start: 559.713, Time: 0, Outcome: WaitUnfinished},
+	}
+	ws := waits(NewTimeline(time.Time{}).lines(lines))
+	if len(ws) != len(expects) {
+		t.Fatalf("Expected %v waits, got %v", len(expects), ws)
+	}
+	for i, e := range expects {
+		w := ws[i]
+		w.Start, w.Time = round(w.Start), round(w.Time)
+		if w != e {
+			t.Errorf("Expected %+v, got %+v", e, w)
+		}
+	}
+}
+
+func round(s float64) float64 {
+	return float64(int64(s*1000+0.5)) / 1000
+}
@@ -0,0 +1,215 @@
+package top
+
+import (
+	"regexp"
+	"sort"
+	"time"
+)
+
+var timeRegexp = regexp.MustCompile(`^([A-Z][a-z]{2}[ ]{1,2}[0-9]{1,2}[ ]{1,2}[0-9]{1,2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?).*`)
+
+// Line is a single line of the test output.
+type Line struct {
+	Time    time.Time
+	Line    string
+	HasTime bool
+}
+
+// Window is a sliding window over the timestamped lines of a test.
+type Window struct {
+	TimedWindow []Line
+	Size        int
+	// Gap is set for the windows found by GapDetection, the line before
+	// and after the gap.
+	Gap bool
+}
+
+// Blocks splits the output of a test into alternating fast and slow blocks.
+// It is the per test entry of stats.json.
+type Blocks struct {
+	offset time.Time
+	Name   string `json:"name"`
+	// Time is the test duration in seconds as reported by ginkgo.
+	Time   float64 `json:"time,omitempty"`
+	Status string  `json:"status,omitempty"`
+	Node   int     `json:"node,omitempty"`
+	// Start and End are the wall-clock times of the test, zero when the
+	// output has no timestamps.
+	Start time.Time `json:"start,omitzero"`
+	End   time.Time `json:"end,omitzero"`
+	// Threshold in seconds used to find the slow blocks.
+	Threshold float64 `json:"threshold"`
+	Blocks    []Block `json:"block"`
+	// Commands are the oc invocations of the test.
+	Commands []Command `json:"commands,omitempty"`
+	// Waits are the waits for builds of the test.
+	Waits []Wait `json:"waits,omitempty"`
+}
+
+// Block is a continuous part of the test output. Start and End are in seconds
+// since the first timestamped line of the test.
+type Block struct {
+	Lines     []string `json:"lines"`
+	Start     float64  `json:"start"`
+	End       float64  `json:"end"`
+	BlockType string   `json:"blockType"`
+}
+
+// lineTime returns the timestamp at the start of the line, without year.
+func lineTime(l string) (time.Time, bool) {
+	m := timeRegexp.FindStringSubmatch(l)
+	if len(m) < 2 {
+		return time.Time{}, false
+	}
+	t, _ := time.Parse(`Jan 2 15:04:05`, m[1])
+	return t, true
+}
+
+func (w *Window) processLine(l Line) bool {
+	if !l.HasTime {
+		return false
+	}
+	if len(w.TimedWindow) < w.Size {
+		w.TimedWindow = append(w.TimedWindow, l)
+	} else {
+		w.TimedWindow = w.TimedWindow[1:]
+		w.TimedWindow = append(w.TimedWindow, l)
+	}
+	return true
+}
+
+func copyWin(w Window) Window {
+	ntw := make([]Line, len(w.TimedWindow))
+	copy(ntw, w.TimedWindow)
+	return Window{ntw, len(w.TimedWindow), w.Gap}
+}
+
+// Time returns the time between the first and the last line.
+func (w Window) Time() time.Duration {
+	if len(w.TimedWindow) == 0 {
+		return 0
+	}
+	return w.TimedWindow[len(w.TimedWindow)-1].Time.Sub(w.TimedWindow[0].Time)
+}
+
+func (blcks *Blocks) since(t time.Time) float64 {
+	return t.Sub(blcks.offset).Seconds()
+}
+
+func (blcks *Blocks) close(w Window) {
+	if len(blcks.Blocks) == 0 {
+		blcks.Blocks = append(blcks.Blocks, Block{})
+	}
+	if len(w.TimedWindow) == 0 {
+		return
+	}
+	b := &blcks.Blocks[len(blcks.Blocks)-1]
+	b.End = blcks.since(w.TimedWindow[len(w.TimedWindow)-1].Time)
+	b.Lines = append(b.Lines, "...")
+	b.Lines = append(b.Lines, w.TimedWindow[len(w.TimedWindow)-1].Line)
+	b.BlockType = "fast"
+}
+
+func (blcks *Blocks) process(w Window, threshold time.Duration) {
+	if len(blcks.Blocks) == 0 {
+		blcks.Blocks = append(blcks.Blocks, Block{})
+		b := &blcks.Blocks[len(blcks.Blocks)-1]
+		b.Lines = make([]string, 0)
+	}
+	b := &blcks.Blocks[len(blcks.Blocks)-1]
+	if len(b.Lines) == 0 && len(w.TimedWindow) > 0 {
+		//init block with first timed line
+		if len(blcks.Blocks) == 1 {
+			blcks.offset = w.TimedWindow[0].Time
+		}
+		b.Start = blcks.since(w.TimedWindow[0].Time)
+		b.Lines = append(b.Lines, w.TimedWindow[0].Line)
+		return
+	}
+	if w.Time() > threshold {
+		b.End = blcks.since(w.TimedWindow[0].Time)
+		b.Lines = append(b.Lines, "...")
+		b.Lines = append(b.Lines, w.TimedWindow[0].Line)
+		b.BlockType = "fast"
+		lines := make([]string, 0)
+		for _, l := range w.TimedWindow {
+			lines = append(lines, l.Line)
+		}
+		sb := Block{
+			lines,
+			blcks.since(w.TimedWindow[0].Time),
+			blcks.since(w.TimedWindow[len(w.TimedWindow)-1].Time),
+			"slow",
+		}
+		blcks.Blocks = append(blcks.Blocks, sb)
+		fb := Block{
+			[]string{w.TimedWindow[len(w.TimedWindow)-1].Line},
+			blcks.since(w.TimedWindow[len(w.TimedWindow)-1].Time),
+			0,
+			"fast",
+		}
+		blcks.Blocks = append(blcks.Blocks, fb)
+	}
+}
+
+// Process finds the slow windows in the lines of a test, slowest first, and
+// splits the lines into fast and slow blocks. The threshold follows the
+// strategy and the overrides of the options.
+func Process(lines []string, opts Options) ([]Window, Blocks, error) {
+	if err := opts.validate(); err != nil {
+		return nil, Blocks{}, err
+	}
+	r := &Report{Tests: []Test{{Lines: lines}}}
+	r.process(opts, opts.Start)
+	return r.Tests[0].Windows, r.Tests[0].Blocks, nil
+}
+
+// process finds the slow windows in the lines normalized by a timeline.
+func process(lines []Line, opts Options) ([]Window, Blocks) {
+	if opts.Detection == GapDetection {
+		return processGaps(lines, opts)
+	}
+	w := make([]Window, 0)
+	b := Blocks{offset: time.Time{}, Blocks: make([]Block, 0)}
+	win := Window{make([]Line, 0), opts.WindowSize, false}
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		win.processLine(l)
+		b.process(win, opts.Threshold)
+		if win.Time() > opts.Threshold {
+			wc := copyWin(win)
+			w = append(w, wc)
+			wi := i + 1
+			for j := 1; j <= opts.WindowSize && wi < len(lines); wi++ {
+				if win.processLine(lines[wi]) {
+					j++
+				}
+			}
+			i = wi
+		}
+	}
+	b.close(win)
+	sort.Slice(w, func(i, j int) bool { return w[i].Time() > w[j].Time() })
+	return w, b
+}
+
+// processGaps finds the gaps between consecutive timestamped lines longer
+// than the threshold, slowest first, and splits the lines into fast blocks and
+// slow blocks of the lines before and after each gap.
+func processGaps(lines []Line, opts Options) ([]Window, Blocks) {
+	w := make([]Window, 0)
+	b := Blocks{offset: time.Time{}, Blocks: make([]Block, 0)}
+	win := Window{make([]Line, 0), 2, true}
+	for _, l := range lines {
+		if !win.processLine(l) {
+			continue
+		}
+		b.process(win, opts.Threshold)
+		if win.Time() > opts.Threshold {
+			w = append(w, copyWin(win))
+		}
+	}
+	b.close(win)
+	sort.SliceStable(w, func(i, j int) bool { return w[i].Time() > w[j].Time() })
+	return w, b
+}
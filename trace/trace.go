@@ -0,0 +1,113 @@
+// Package trace exports the parsed tests as Chrome Trace Event JSON, to be
+// opened in chrome://tracing or Perfetto.
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+// maxLines limits the log excerpts in the event args.
+const maxLines = 20
+
+// Event is a trace event, complete events have the "X" phase and metadata
+// events the "M" phase. Times are in microseconds.
+type Event struct {
+	Name     string                 `json:"name"`
+	Category string                 `json:"cat,omitempty"`
+	Phase    string                 `json:"ph"`
+	Time     int64                  `json:"ts"`
+	Duration int64                  `json:"dur"`
+	Process  int                    `json:"pid"`
+	Thread   int                    `json:"tid"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+}
+
+// Trace is the JSON object format of the trace.
+type Trace struct {
+	TraceEvents     []Event `json:"traceEvents"`
+	DisplayTimeUnit string  `json:"displayTimeUnit"`
+}
+
+func micros(d time.Duration) int64 {
+	return int64(d / time.Microsecond)
+}
+
+func excerpt(lines []string) []string {
+	if len(lines) > maxLines {
+		return append(append([]string{}, lines[:maxLines]...), "...")
+	}
+	return lines
+}
+
+// New converts the tests into a trace with a process per ginkgo node and a
+// thread per test. The blocks, windows, phases, commands and waits of a test
+// are on its thread with the log lines as args.
+func New(r *top.Report) Trace {
+	tr := Trace{TraceEvents: make([]Event, 0), DisplayTimeUnit: "ms"}
+	s, origin := r.Starts()
+	ts := func(t time.Time) int64 {
+		return micros(t.Sub(origin))
+	}
+	nodes := make(map[int]bool)
+	for i, t := range r.Tests {
+		pid, tid := t.Node, i+1
+		if !nodes[pid] {
+			nodes[pid] = true
+			name := fmt.Sprintf("node %v", pid)
+			if pid == 0 {
+				name = "suite"
+			}
+			tr.TraceEvents = append(tr.TraceEvents, Event{Name: "process_name", Phase: "M", Process: pid, Args: map[string]interface{}{"name": name}})
+		}
+		tr.TraceEvents = append(tr.TraceEvents, Event{Name: "thread_name", Phase: "M", Process: pid, Thread: tid, Args: map[string]interface{}{"name": t.Name()}})
+		add := func(name, cat string, start time.Time, d time.Duration, args map[string]interface{}) {
+			tr.TraceEvents = append(tr.TraceEvents, Event{name, cat, "X", ts(start), micros(d), pid, tid, args})
+		}
+
+		start := s[i]
+		add(t.Name(), "test", start, top.Seconds(t.Time), map[string]interface{}{"status": t.Status, "slow": t.Slow})
+		for _, b := range t.Blocks.Blocks {
+			add(b.BlockType, "block", start.Add(top.Seconds(b.Start)), top.Seconds(b.End-b.Start), map[string]interface{}{"lines": excerpt(b.Lines)})
+		}
+		for _, w := range t.Windows {
+			if len(w.TimedWindow) == 0 {
+				continue
+			}
+			lines := make([]string, 0, len(w.TimedWindow))
+			for _, l := range w.TimedWindow {
+				lines = append(lines, l.Line)
+			}
+			name := "window"
+			if w.Gap {
+				name = "gap"
+			}
+			add(name, "window", w.TimedWindow[0].Time, w.Time(), map[string]interface{}{"lines": excerpt(lines)})
+		}
+		for _, p := range t.Phases.Phases {
+			args := map[string]interface{}{"start": p.StartLine}
+			if p.End.IsZero() {
+				args["unfinished"] = true
+			} else {
+				args["end"] = p.EndLine
+			}
+			add(p.Name, "phase", p.Start, p.Duration(), args)
+		}
+		for _, c := range t.Commands {
+			add("oc "+c.Verb, "command", start.Add(top.Seconds(c.Start)), top.Seconds(c.Time), map[string]interface{}{"resource": c.Resource, "namespace": c.Namespace, "line": c.Line})
+		}
+		for _, w := range t.Waits {
+			add("wait "+w.Object, "wait", start.Add(top.Seconds(w.Start)), top.Seconds(w.Time), map[string]interface{}{"outcome": w.Outcome})
+		}
+	}
+	return tr
+}
+
+// Write writes the tests as Chrome Trace Event JSON.
+func Write(w io.Writer, r *top.Report) error {
+	return json.NewEncoder(w).Encode(New(r))
+}
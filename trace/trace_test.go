@@ -0,0 +1,79 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+const log = `------------------------------
+[builds] pipeline
+/go/src/github.com/openshift/origin/test/extended/builds/pipeline.go:437
+Apr  3 11:48:35.000: INFO: Running 'oc start-build --namespace=ns sample'
+Apr  3 11:48:40.000: INFO: Waiting for sample-1 to complete
+2018-04-03T11:49:00Z Step 1/3 : FROM centos
+2018-04-03T11:49:30Z Successfully built 0123456789ab
+Apr  3 11:57:40.000: INFO: Done waiting for sample-1: util.BuildResult{BuildSuccess:true}
+
+• [SLOW TEST:545.000 seconds]
+------------------------------
+[builds] digest
+/go/src/github.com/openshift/origin/test/extended/builds/digest.go:65
+Apr  3 11:58:00.000: INFO: Running 'oc delete bc sample'
+Apr  3 11:58:02.000: INFO: done
+
+• [SLOW TEST:6.000 seconds]
+`
+
+func find(events []Event, cat, name string) (Event, bool) {
+	for _, e := range events {
+		if e.Category == cat && e.Name == name {
+			return e, true
+		}
+	}
+	return Event{}, false
+}
+
+func TestNew(t *testing.T) {
+	r, err := top.Parse(strings.NewReader(log), top.DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr := New(r)
+	expects := []struct {
+		cat, name string
+		ts, dur   int64
+		tid       int
+	}{
+		{"test", "/test/extended/builds/pipeline.go:437", 0, 545000000, 1},
+		{"command", "oc start-build", 0, 5000000, 1},
+		{"wait", "wait sample-1", 5000000, 540000000, 1},
+		{"phase", "docker build", 25000000, 30000000, 1},
+		{"test", "/test/extended/builds/digest.go:65", 565000000, 6000000, 2},
+		{"command", "oc delete", 565000000, 2000000, 2},
+	}
+	for _, e := range expects {
+		ev, ok := find(tr.TraceEvents, e.cat, e.name)
+		if !ok {
+			t.Errorf("Expected %v %v event", e.cat, e.name)
+			continue
+		}
+		if ev.Phase != "X" || ev.Time != e.ts || ev.Duration != e.dur || ev.Thread != e.tid {
+			t.Errorf("Expected %v at %v taking %v on %v, got %+v", e.name, e.ts, e.dur, e.tid, ev)
+		}
+	}
+	if ev, ok := find(tr.TraceEvents, "", "thread_name"); !ok || ev.Args["name"] != "/test/extended/builds/pipeline.go:437" {
+		t.Errorf("Expected thread name of the first test, got %+v", ev)
+	}
+	buf := &bytes.Buffer{}
+	if err := Write(buf, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded := Trace{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.TraceEvents) != len(tr.TraceEvents) {
+		t.Errorf("Expected %v events in json, got %v, %v", len(tr.TraceEvents), len(decoded.TraceEvents), err)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wozniakjan/test_eval/top"
+)
+
+// Upload limits of the analyze endpoint.
+const (
+	defaultMaxUpload     = 32 << 20
+	defaultMaxLog        = 256 << 20
+	defaultUploadTimeout = time.Minute
+	maxFormValue         = 1 << 10
+)
+
+var errLogTooLarge = errors.New("log too large")
+
+// uploadReader stops reading the log when it is larger than n bytes or when
+// the upload times out. The timeout only covers reading, the windows of the
+// read log are found without it.
+type uploadReader struct {
+	ctx context.Context
+	r   io.Reader
+	n   int64
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	if err := u.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if u.n <= 0 {
+		return 0, errLogTooLarge
+	}
+	if int64(len(p)) > u.n {
+		p = p[:u.n]
+	}
+	n, err := u.r.Read(p)
+	u.n -= int64(n)
+	return n, err
+}
+
+// uploadOptions are the analyze options of an uploaded log, the flags of the
+// analyze command given as query or form parameters.
+type uploadOptions struct {
+	Threshold  float64       `json:"threshold"`
+	WindowSize int           `json:"window"`
+	Count      int           `json:"count"`
+	Detection  top.Detection `json:"detection"`
+	Strategy   top.Strategy  `json:"strategy"`
+	Relative   float64       `json:"relative"`
+	Percentile float64       `json:"percentile"`
+	format     string
+}
+
+func parseUploadOptions(v url.Values) (uploadOptions, error) {
+	o := uploadOptions{Threshold: 120, WindowSize: 5, Count: 5, Detection: top.WindowDetection, Strategy: top.FixedThreshold, Relative: 0.2, Percentile: 99, format: "html"}
+	var err error
+	for name, f := range map[string]*float64{"t": &o.Threshold, "relative": &o.Relative, "percentile": &o.Percentile} {
+		if s := v.Get(name); s != "" {
+			if *f, err = strconv.ParseFloat(s, 64); err != nil || math.IsNaN(*f) || math.IsInf(*f, 0) {
+				return o, fmt.Errorf("invalid %v %q", name, s)
+			}
+		}
+	}
+	if s := v.Get("w"); s != "" {
+		if o.WindowSize, err = strconv.Atoi(s); err != nil {
+			return o, fmt.Errorf("invalid window size %q", s)
+		}
+	}
+	if s := v.Get("c"); s != "" {
+		if o.Count, err = strconv.Atoi(s); err != nil {
+			return o, fmt.Errorf("invalid count %q", s)
+		}
+	}
+	if s := v.Get("detect"); s != "" {
+		o.Detection = top.Detection(s)
+	}
+	if s := v.Get("strategy"); s != "" {
+		o.Strategy = top.Strategy(s)
+	}
+	if s := v.Get("format"); s != "" {
+		if s != "html" && s != "json" {
+			return o, fmt.Errorf("invalid format %q, html or json", s)
+		}
+		o.format = s
+	}
+	return o, nil
+}
+
+// parseUpload parses a plain or gzipped log of at most max bytes.
+func parseUpload(ctx context.Context, r io.Reader, max int64, opts top.Options) (*top.Report, error) {
+	br := bufio.NewReader(r)
+	var in io.Reader = br
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		in = gz
+	}
+	return top.Parse(&uploadReader{ctx, in, max}, opts)
+}
+
+// uploadedLog returns the log of the request, the "log" file of a multipart
+// form or the whole body, and the options from the query merged with the
+// form values sent before the file.
+func uploadedLog(r *http.Request) (io.Reader, url.Values, error) {
+	values := r.URL.Query()
+	mr, err := r.MultipartReader()
+	if err == http.ErrNotMultipart {
+		return r.Body, values, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("log file is required")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if part.FormName() == "log" {
+			return part, values, nil
+		}
+		if err := addFormValue(values, part); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func addFormValue(values url.Values, part *multipart.Part) error {
+	value, err := ioutil.ReadAll(io.LimitReader(part, maxFormValue+1))
+	if err != nil {
+		return err
+	}
+	if len(value) > maxFormValue {
+		return fmt.Errorf("form value %v too large", part.FormName())
+	}
+	if s := strings.TrimSpace(string(value)); s != "" && values.Get(part.FormName()) == "" {
+		values.Set(part.FormName(), s)
+	}
+	return nil
+}
+
+func uploadStatus(err error) int {
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytes), errors.Is(err, errLogTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+// analyze serves the upload form on GET and analyzes the posted log in
+// memory, returning the report of the slowest tests as html or json.
+func (s *server) analyze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		page(w, "analyze", nil)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), s.uploadTimeout)
+	defer cancel()
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload)
+	log, values, err := uploadedLog(r)
+	if err != nil {
+		http.Error(w, err.Error(), uploadStatus(err))
+		return
+	}
+	o, err := parseUploadOptions(values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	report, err := parseUpload(ctx, log, s.maxLog, top.Options{
+		WindowSize: o.WindowSize,
+		Threshold:  top.Seconds(o.Threshold),
+		Detection:  o.Detection,
+		Strategy:   o.Strategy,
+		Relative:   o.Relative,
+		Percentile: o.Percentile,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), uploadStatus(err))
+		return
+	}
+	summary := report.Summary()
+	tests := slowest(report, o.Count)
+	if o.format == "json" {
+		stats := make([]top.Blocks, len(tests))
+		for i, t := range tests {
+			stats[i] = t.Blocks
+		}
+		render(w, "application/json", func(w io.Writer) error {
+			return json.NewEncoder(w).Encode(struct {
+				Options uploadOptions `json:"options"`
+				Summary top.Summary   `json:"summary"`
+				Tests   []top.Blocks  `json:"tests"`
+			}{o, summary, stats})
+		})
+		return
+	}
+	rt := make([]reportTest, len(tests))
+	for i, t := range tests {
+		rt[i] = reportTest{t.Blocks, strings.Join(t.Lines, "\n")}
+	}
+	render(w, "text/html; charset=utf-8", func(w io.Writer) error { return renderReport(w, rt) })
+}
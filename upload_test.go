@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wozniakjan/test_eval/history"
+	"github.com/wozniakjan/test_eval/top"
+)
+
+var uploadLog = `------------------------------
+[builds] test
+/go/src/github.com/openshift/origin/test/extended/builds/pipeline.go:437
+Apr  3 11:48:35.747: INFO: Running 'oc new-app'
+Apr  3 11:48:40.287: INFO: Waiting for openshift-jee-sample-1 to complete
+Apr  3 11:57:41.804: INFO: Done waiting for openshift-jee-sample-1 <script>
+
+• [SLOW TEST:552.000 seconds]
+------------------------------
+[builds] other test
+/go/src/github.com/openshift/origin/test/extended/builds/digest.go:65
+Apr  3 11:58:00.000: INFO: Running 'oc create'
+Apr  3 11:58:01.000: INFO: Running 'oc delete'
+
+• [SLOW TEST:6.500 seconds]
+`
+
+func gzipped(t *testing.T, s string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func multipartLog(t *testing.T, fields map[string]string, log []byte) (string, []byte) {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if log != nil {
+		f, err := mw.CreateFormFile("log", "console.log.gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(log)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return mw.FormDataContentType(), buf.Bytes()
+}
+
+type uploadResult struct {
+	Options uploadOptions `json:"options"`
+	Summary top.Summary   `json:"summary"`
+	Tests   []top.Blocks  `json:"tests"`
+}
+
+func TestAnalyzeUpload(t *testing.T) {
+	s := newServer("outs", history.Store{Dir: "history"})
+	h := httptest.NewServer(s)
+	defer h.Close()
+
+	form, body := multipartLog(t, map[string]string{"t": "100", "c": "1", "format": "json"}, gzipped(t, uploadLog))
+	empty, noLog := multipartLog(t, map[string]string{"t": "100"}, nil)
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		body        []byte
+		code        int
+		tests       int
+		threshold   float64
+	}{
+		{"plain", "?format=json&t=60&w=2&c=0", "text/plain", []byte(uploadLog), 200, 2, 60},
+		{"gzip", "?format=json", "application/gzip", gzipped(t, uploadLog), 200, 2, 120},
+		{"multipart", "", form, body, 200, 1, 100},
+		{"query over form", "?t=50", form, body, 200, 1, 50},
+		{"no log", "", empty, noLog, 400, 0, 0},
+		{"invalid threshold", "?t=abc", "text/plain", []byte(uploadLog), 400, 0, 0},
+		{"invalid window", "?w=0", "text/plain", []byte(uploadLog), 400, 0, 0},
+		{"invalid format", "?format=xml", "text/plain", []byte(uploadLog), 400, 0, 0},
+		{"invalid strategy", "?strategy=other", "text/plain", []byte(uploadLog), 400, 0, 0},
+		{"invalid percentile", "?strategy=percentile&percentile=abc", "text/plain", []byte(uploadLog), 400, 0, 0},
+		{"NaN percentile", "?strategy=percentile&percentile=NaN", "text/plain", []byte(uploadLog), 400, 0, 0},
+		{"infinite relative", "?strategy=relative&relative=Inf", "text/plain", []byte(uploadLog), 400, 0, 0},
+		{"NaN threshold", "?t=NaN", "text/plain", []byte(uploadLog), 400, 0, 0},
+		{"invalid log", "", "text/plain", []byte("• [SLOW TEST:abc seconds]\n"), 400, 0, 0},
+	}
+	for _, test := range tests {
+		resp, err := http.Post(h.URL+"/analyze"+test.query, test.contentType, bytes.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.code {
+			t.Errorf("%v: Expected %v, got %v: %s", test.name, test.code, resp.StatusCode, data)
+			continue
+		}
+		if test.code != 200 {
+			continue
+		}
+		result := uploadResult{}
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+		if len(result.Tests) != test.tests || result.Summary.Tests != 2 || result.Options.Threshold != test.threshold {
+			t.Errorf("%v: Expected %v of 2 tests with threshold %v, got %+v", test.name, test.tests, test.threshold, result)
+		}
+		if result.Tests[0].Name != "/test/extended/builds/pipeline.go:437" {
+			t.Errorf("%v: Expected the slowest test first, got %v", test.name, result.Tests[0].Name)
+		}
+	}
+
+	resp, err := http.Post(h.URL+"/analyze?format=json&strategy=percentile&percentile=50", "text/plain", strings.NewReader(uploadLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := uploadResult{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if th := result.Summary.Thresholds; err != nil || th.Strategy != top.PercentileThreshold || th.Percentile != 50 || th.Gaps != 3 || th.Threshold != 4.54 {
+		t.Errorf("Expected p50 of 3 gaps, 4.54s, got %+v, %v", th, err)
+	}
+
+	resp, err = http.Post(h.URL+"/analyze", "text/plain", strings.NewReader(uploadLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	page := string(data)
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("Expected html report, got %v %v", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(page, `"name":"/test/extended/builds/pipeline.go:437"`) || strings.Contains(page, "sample-1 <script>") {
+		t.Errorf("Expected the escaped test output in the report")
+	}
+
+	resp, err = http.Get(h.URL + "/analyze")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(data), `enctype="multipart/form-data"`) {
+		t.Errorf("Expected the upload form, got %s", data)
+	}
+}
+
+func TestAnalyzeUploadLimits(t *testing.T) {
+	s := newServer("outs", history.Store{Dir: "history"})
+	h := httptest.NewServer(s)
+	defer h.Close()
+	post := func(body []byte) int {
+		resp, err := http.Post(h.URL+"/analyze", "application/octet-stream", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	s.maxUpload = 100
+	if code := post([]byte(uploadLog)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected %v for a large upload, got %v", http.StatusRequestEntityTooLarge, code)
+	}
+	s.maxUpload, s.maxLog = defaultMaxUpload, 1000
+	bomb := gzipped(t, strings.Repeat(uploadLog, 100))
+	if len(bomb) > 1000 {
+		t.Fatalf("Expected the compressed log smaller than the limit, got %v bytes", len(bomb))
+	}
+	if code := post(bomb); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected %v for a large decompressed log, got %v", http.StatusRequestEntityTooLarge, code)
+	}
+	s.maxLog, s.uploadTimeout = defaultMaxLog, 0
+	if code := post([]byte(uploadLog)); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected %v on timeout, got %v", http.StatusServiceUnavailable, code)
+	}
+}